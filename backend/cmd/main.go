@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"ai-blog-backend/internal/handlers"
@@ -59,12 +62,19 @@ func main() {
 	// Auto-migrate database schema
 	err = db.AutoMigrate(
 		&models.Blog{},
+		&models.BlogRevision{},
+		&models.SlugAlias{},
 		&models.Comment{},
 		&models.Like{},
 		&models.UserProfile{},
 		&models.UserFollow{},
 		&models.UserActivity{},
 		&models.ReadingList{},
+		&models.AIUsage{},
+		&models.AIJob{},
+		&models.BlogEmbedding{},
+		&models.PromptTemplate{},
+		&models.AIResponseCache{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
@@ -86,12 +96,31 @@ func main() {
 		log.Printf("Warning: Could not create unique index for reading_lists: %v", err)
 	}
 
+	err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_blogs_translation_locale ON blogs (translation_group_id, locale) WHERE deleted_at IS NULL").Error
+	if err != nil {
+		log.Printf("Warning: Could not create unique index for blog translations: %v", err)
+	}
+
+	// Full-text search column and index for blog search
+	searchSchema := []string{
+		"ALTER TABLE blogs ADD COLUMN IF NOT EXISTS search_vector tsvector",
+		"CREATE INDEX IF NOT EXISTS idx_blogs_search_vector ON blogs USING GIN (search_vector)",
+	}
+
+	for _, schemaSQL := range searchSchema {
+		err = db.Exec(schemaSQL).Error
+		if err != nil {
+			log.Printf("Warning: Could not set up blog search: %v", err)
+		}
+	}
+
 	// Create performance indexes for frequently queried fields
 	performanceIndexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_blogs_status ON blogs (status) WHERE deleted_at IS NULL",
 		"CREATE INDEX IF NOT EXISTS idx_blogs_author_status ON blogs (author_id, status) WHERE deleted_at IS NULL",
 		"CREATE INDEX IF NOT EXISTS idx_blogs_slug ON blogs (slug) WHERE deleted_at IS NULL",
 		"CREATE INDEX IF NOT EXISTS idx_blogs_created_at ON blogs (created_at DESC) WHERE deleted_at IS NULL",
+		"CREATE INDEX IF NOT EXISTS idx_blogs_scheduled_at ON blogs (scheduled_at) WHERE status = 'scheduled' AND deleted_at IS NULL",
 		"CREATE INDEX IF NOT EXISTS idx_comments_blog_status ON comments (blog_id, status) WHERE deleted_at IS NULL",
 		"CREATE INDEX IF NOT EXISTS idx_comments_status ON comments (status) WHERE deleted_at IS NULL",
 		"CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments (created_at ASC) WHERE deleted_at IS NULL",
@@ -107,22 +136,305 @@ func main() {
 	}
 
 	// Initialize services
-	aiService := services.NewAIService(os.Getenv("OPENAI_API_KEY"))
+	aiGenerator, err := services.NewTextGenerator(services.AIConfig{
+		Provider: os.Getenv("AI_PROVIDER"),
+		APIKey:   os.Getenv("OPENAI_API_KEY"),
+		BaseURL:  os.Getenv("AI_BASE_URL"),
+		Model:    os.Getenv("AI_MODEL"),
+	})
+	if err != nil {
+		log.Fatal("Failed to configure AI provider:", err)
+	}
+
+	// Retry rate limits and provider errors with jittered backoff. Background jobs
+	// are retried as a whole instead.
+	aiRetries := services.DefaultRetryPolicy
+	if raw := os.Getenv("AI_MAX_ATTEMPTS"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			aiRetries.MaxAttempts = parsed
+		} else {
+			log.Printf("Warning: Invalid AI_MAX_ATTEMPTS %q, using %d", raw, aiRetries.MaxAttempts)
+		}
+	}
+	aiGenerator = services.NewRetryingGenerator(aiGenerator, aiRetries)
+
+	// Cache AI responses to repeated requests: memory (default), db or off
+	aiCacheTTL := 24 * time.Hour
+	if raw := os.Getenv("AI_CACHE_TTL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			aiCacheTTL = parsed
+		} else {
+			log.Printf("Warning: Invalid AI_CACHE_TTL %q, using %v", raw, aiCacheTTL)
+		}
+	}
+	aiCacheSize := 1000
+	if raw := os.Getenv("AI_CACHE_SIZE"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			aiCacheSize = parsed
+		} else {
+			log.Printf("Warning: Invalid AI_CACHE_SIZE %q, using %d", raw, aiCacheSize)
+		}
+	}
+	switch mode := os.Getenv("AI_CACHE"); mode {
+	case "", services.CacheMemory:
+		aiGenerator = services.NewCachingGenerator(aiGenerator, services.NewMemoryCache(aiCacheSize), aiCacheTTL)
+	case services.CacheDatabase:
+		aiGenerator = services.NewCachingGenerator(aiGenerator, services.NewDBCache(db), aiCacheTTL)
+	case services.CacheOff:
+	default:
+		log.Printf("Warning: Unknown AI_CACHE %q, caching AI responses in memory", mode)
+		aiGenerator = services.NewCachingGenerator(aiGenerator, services.NewMemoryCache(aiCacheSize), aiCacheTTL)
+	}
+
+	promptService := services.NewPromptService(db)
+
+	// How long each AI operation may take, e.g. AI_TIMEOUTS=generate_meta=20s,translate_blog=10m
+	aiTimeouts := services.DefaultAITimeouts
+	if raw := os.Getenv("AI_TIMEOUT"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed >= 0 {
+			aiTimeouts.Default = parsed
+		} else {
+			log.Printf("Warning: Invalid AI_TIMEOUT %q, using %v", raw, aiTimeouts.Default)
+		}
+	}
+	if raw := os.Getenv("AI_TIMEOUTS"); raw != "" {
+		operations := make(map[string]time.Duration, len(aiTimeouts.Operations))
+		for operation, timeout := range aiTimeouts.Operations {
+			operations[operation] = timeout
+		}
+		for _, entry := range strings.Split(raw, ",") {
+			operation, value, _ := strings.Cut(strings.TrimSpace(entry), "=")
+			parsed, err := time.ParseDuration(value)
+			if operation == "" || err != nil || parsed < 0 {
+				log.Printf("Warning: Invalid AI_TIMEOUTS entry %q, ignoring it", entry)
+				continue
+			}
+			operations[operation] = parsed
+		}
+		aiTimeouts.Operations = operations
+	}
+	aiService := services.NewAIService(aiGenerator, promptService, aiTimeouts)
+
+	// Per-user AI token quotas; 0 disables a limit
+	aiQuota := services.AIQuota{DailyTokens: 50000, MonthlyTokens: 500000}
+	if raw := os.Getenv("AI_DAILY_TOKEN_QUOTA"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil {
+			aiQuota.DailyTokens = parsed
+		} else {
+			log.Printf("Warning: Invalid AI_DAILY_TOKEN_QUOTA %q, using %d", raw, aiQuota.DailyTokens)
+		}
+	}
+	if raw := os.Getenv("AI_MONTHLY_TOKEN_QUOTA"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil {
+			aiQuota.MonthlyTokens = parsed
+		} else {
+			log.Printf("Warning: Invalid AI_MONTHLY_TOKEN_QUOTA %q, using %d", raw, aiQuota.MonthlyTokens)
+		}
+	}
+	usageService := services.NewUsageService(db, aiQuota)
 	blogService := services.NewBlogService(db)
-	commentService := services.NewCommentService(db)
+	revisionService := services.NewRevisionService(db, blogService)
+
+	// Optional automatic comment moderation: off (default), heuristic or ai
+	var commentModerator *services.CommentModerator
+	approveBelow, rejectFrom := 0.2, 0.9
+	if raw := os.Getenv("COMMENT_AUTO_APPROVE_BELOW"); raw != "" {
+		if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+			approveBelow = parsed
+		} else {
+			log.Printf("Warning: Invalid COMMENT_AUTO_APPROVE_BELOW %q, using %v", raw, approveBelow)
+		}
+	}
+	if raw := os.Getenv("COMMENT_AUTO_REJECT_FROM"); raw != "" {
+		if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+			rejectFrom = parsed
+		} else {
+			log.Printf("Warning: Invalid COMMENT_AUTO_REJECT_FROM %q, using %v", raw, rejectFrom)
+		}
+	}
+	switch mode := os.Getenv("COMMENT_MODERATION"); mode {
+	case "", "off":
+	case "heuristic":
+		commentModerator = services.NewCommentModerator(services.HeuristicClassifier{}, approveBelow, rejectFrom)
+	case "ai":
+		commentModerator = services.NewCommentModerator(services.NewAIClassifier(aiService), approveBelow, rejectFrom)
+	default:
+		log.Printf("Warning: Unknown COMMENT_MODERATION %q, leaving comments for manual approval", mode)
+	}
+	commentService := services.NewCommentService(db, commentModerator)
+
 	likeService := services.NewLikeService(db)
 	userService := services.NewUserService(db)
 
+	site := services.SiteConfig{
+		URL:         os.Getenv("SITE_URL"),
+		Title:       os.Getenv("SITE_TITLE"),
+		Description: os.Getenv("SITE_DESCRIPTION"),
+	}
+	if site.URL == "" {
+		site.URL = "http://localhost:3000"
+	}
+	if site.Title == "" {
+		site.Title = "AI Blog"
+	}
+	feedService := services.NewFeedService(db, site)
+
+	robots := services.RobotsConfig{
+		DisallowAll: os.Getenv("ROBOTS_DISALLOW_ALL") == "true",
+		Disallow:    []string{"/admin", "/create", "/profile"},
+		SitemapURL:  os.Getenv("SITEMAP_URL"),
+	}
+	if raw, ok := os.LookupEnv("ROBOTS_DISALLOW"); ok {
+		robots.Disallow = nil
+		for _, path := range strings.Split(raw, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				robots.Disallow = append(robots.Disallow, path)
+			}
+		}
+	}
+	sitemapService := services.NewSitemapService(db, site, robots)
+
+	// Embeddings for related posts, kept up to date as blogs are saved. The
+	// openai-compatible provider needs EMBEDDING_MODEL, e.g. nomic-embed-text.
+	embeddingProvider := os.Getenv("EMBEDDING_PROVIDER")
+	if embeddingProvider == "" {
+		embeddingProvider = os.Getenv("AI_PROVIDER")
+	}
+	embedder, err := services.NewEmbedder(services.AIConfig{
+		Provider: embeddingProvider,
+		APIKey:   os.Getenv("OPENAI_API_KEY"),
+		BaseURL:  os.Getenv("AI_BASE_URL"),
+		Model:    os.Getenv("EMBEDDING_MODEL"),
+	})
+	if err != nil {
+		log.Fatal("Failed to configure embedding provider:", err)
+	}
+	embeddingService := services.NewEmbeddingService(db, embedder)
+	blogService.AddSaveListener(embeddingService.OnBlogSaved)
+	go func() {
+		if err := embeddingService.IndexMissing(); err != nil {
+			log.Printf("Warning: Could not embed existing blogs: %v", err)
+		}
+	}()
+
+	// TL;DR summaries and key takeaways: extractive (default), ai or off
+	summaryMode := os.Getenv("BLOG_SUMMARIES")
+	switch summaryMode {
+	case "", "extractive", "ai", "off":
+	default:
+		log.Printf("Warning: Unknown BLOG_SUMMARIES %q, using extractive summaries", summaryMode)
+	}
+	if summaryMode != "off" {
+		var summaryAI *services.AIService
+		if summaryMode == "ai" {
+			summaryAI = aiService
+		}
+		summaryService := services.NewSummaryService(db, summaryAI, usageService)
+		blogService.AddSaveListener(summaryService.OnBlogSaved)
+		go func() {
+			if err := summaryService.SummarizeMissing(); err != nil {
+				log.Printf("Warning: Could not summarize existing blogs: %v", err)
+			}
+		}()
+	}
+
+	if err := blogService.RebuildSearchIndex(); err != nil {
+		log.Printf("Warning: Could not rebuild blog search index: %v", err)
+	}
+
+	// Start the background publisher for scheduled blogs
+	schedulerInterval := time.Minute
+	if raw := os.Getenv("PUBLISH_SCHEDULER_INTERVAL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			schedulerInterval = parsed
+		} else {
+			log.Printf("Warning: Invalid PUBLISH_SCHEDULER_INTERVAL %q, using %s", raw, schedulerInterval)
+		}
+	}
+	services.NewPublishScheduler(blogService, schedulerInterval).Start(context.Background())
+
+	// Start the background workers for queued AI jobs
+	jobService := services.NewAIJobService(db, aiService, usageService, userService)
+	jobWorkers := 2
+	if raw := os.Getenv("AI_JOB_WORKERS"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil {
+			jobWorkers = parsed
+		} else {
+			log.Printf("Warning: Invalid AI_JOB_WORKERS %q, using %d", raw, jobWorkers)
+		}
+	}
+	jobPollInterval := 2 * time.Second
+	if raw := os.Getenv("AI_JOB_POLL_INTERVAL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			jobPollInterval = parsed
+		} else {
+			log.Printf("Warning: Invalid AI_JOB_POLL_INTERVAL %q, using %s", raw, jobPollInterval)
+		}
+	}
+	services.NewAIJobWorkerPool(jobService, jobWorkers, jobPollInterval).Start(context.Background())
+
+	askService := services.NewAskService(db, aiService)
+
+	// Ask-the-blog is public, so it's limited per client IP and by a daily token
+	// budget shared by all readers; 0 disables a limit
+	askRateLimit := 10
+	if raw := os.Getenv("ASK_RATE_LIMIT"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil {
+			askRateLimit = parsed
+		} else {
+			log.Printf("Warning: Invalid ASK_RATE_LIMIT %q, using %d per minute", raw, askRateLimit)
+		}
+	}
+	askDailyBudget := 200000
+	if raw := os.Getenv("ASK_DAILY_TOKEN_BUDGET"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil {
+			askDailyBudget = parsed
+		} else {
+			log.Printf("Warning: Invalid ASK_DAILY_TOKEN_BUDGET %q, using %d", raw, askDailyBudget)
+		}
+	}
+	seoService := services.NewSEOAuditService(aiService, site.URL)
+	altTextService := services.NewAltTextService(blogService, aiService)
+
 	// Initialize handlers
-	aiHandler := handlers.NewAIHandler(aiService)
-	blogHandler := handlers.NewBlogHandler(blogService, aiService)
+	aiHandler := handlers.NewAIHandler(aiService, usageService, blogService, jobService, seoService, altTextService, userService)
+	blogHandler := handlers.NewBlogHandler(blogService, aiService, embeddingService)
+	revisionHandler := handlers.NewRevisionHandler(revisionService)
 	commentHandler := handlers.NewCommentHandler(commentService)
 	likeHandler := handlers.NewLikeHandler(likeService)
 	userHandler := handlers.NewUserHandler(userService)
+	feedHandler := handlers.NewFeedHandler(feedService)
+	sitemapHandler := handlers.NewSitemapHandler(sitemapService)
+	askHandler := handlers.NewAskHandler(askService, usageService, askDailyBudget)
+	promptHandler := handlers.NewPromptHandler(promptService)
+
+	// Clerk user IDs allowed to use the admin API
+	var adminIDs []string
+	for _, id := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			adminIDs = append(adminIDs, id)
+		}
+	}
 
 	// Initialize Gin router
 	r := gin.Default()
 
+	// Client IPs, used for rate limits, are only read from X-Forwarded-For when the
+	// request comes through one of these proxies (comma-separated IPs or CIDRs).
+	// By default that's a proxy on the same host or private network.
+	trustedProxies := []string{"127.0.0.1", "::1", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+	if raw, ok := os.LookupEnv("TRUSTED_PROXIES"); ok {
+		trustedProxies = nil
+		for _, proxy := range strings.Split(raw, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				trustedProxies = append(trustedProxies, proxy)
+			}
+		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "https://your-domain.com"},
@@ -137,8 +449,10 @@ func main() {
 	{
 		// Blog routes (public)
 		api.GET("/blogs", blogHandler.GetBlogs)
+		api.GET("/blogs/search", blogHandler.SearchBlogs)
 		api.GET("/blogs/:id", blogHandler.GetBlog)
 		api.GET("/blogs/slug/:slug", blogHandler.GetBlogBySlug)
+		api.GET("/blogs/:id/related", blogHandler.GetRelatedBlogs)
 		api.GET("/blogs/:id/comments", commentHandler.GetComments)
 		api.POST("/blogs/:id/comments", commentHandler.AddComment)
 
@@ -147,6 +461,9 @@ func main() {
 		api.POST("/blogs/:id/like", likeHandler.ToggleLike)
 		api.POST("/blogs/:id/share", likeHandler.IncrementShare)
 
+		// Ask-the-blog (public)
+		api.POST("/ask", middleware.RateLimit(askRateLimit, time.Minute), askHandler.Ask)
+
 		// Debug endpoint (temporarily public)
 		api.GET("/users/debug", userHandler.GetCurrentUser)
 
@@ -160,10 +477,27 @@ func main() {
 			protected.POST("/blogs/publish", blogHandler.PublishBlog)
 			protected.PUT("/blogs/:id", blogHandler.UpdateBlog)
 			protected.DELETE("/blogs/:id", blogHandler.DeleteBlog)
+			protected.POST("/blogs/:id/translate", aiHandler.TranslateBlog)
+			protected.POST("/blogs/:id/seo-audit", aiHandler.AuditSEO)
+			protected.POST("/blogs/:id/alt-text", aiHandler.SuggestAltText)
+			protected.POST("/blogs/:id/alt-text/apply", aiHandler.ApplyAltText)
+
+			// Revision history
+			protected.GET("/blogs/:id/revisions", revisionHandler.ListRevisions)
+			protected.GET("/blogs/:id/revisions/diff", revisionHandler.DiffRevisions)
+			protected.GET("/blogs/:id/revisions/:revision", revisionHandler.GetRevision)
+			protected.POST("/blogs/:id/revisions/:revision/restore", revisionHandler.RestoreRevision)
 
 			// AI content generation
 			protected.POST("/ai/generate-content", aiHandler.GenerateContent)
+			protected.POST("/ai/generate-content/stream", aiHandler.GenerateContentStream)
 			protected.POST("/ai/generate-meta", aiHandler.GenerateMeta)
+			protected.POST("/ai/transform", aiHandler.TransformText)
+			protected.POST("/ai/outline", aiHandler.GenerateOutline)
+			protected.POST("/ai/generate-from-outline", aiHandler.GenerateFromOutline)
+			protected.GET("/ai/usage", aiHandler.GetUsage)
+			protected.POST("/ai/jobs", aiHandler.CreateJob)
+			protected.GET("/ai/jobs/:id", aiHandler.GetJob)
 
 			// Comment moderation
 			protected.GET("/comments/pending", commentHandler.GetPendingComments)
@@ -174,6 +508,8 @@ func main() {
 			protected.GET("/users/me", userHandler.GetCurrentUser) // Debug endpoint
 			protected.GET("/users/profile", userHandler.GetProfile)
 			protected.PUT("/users/profile", userHandler.UpdateProfile)
+			protected.GET("/users/voice-profile", userHandler.GetVoiceProfile)
+			protected.PUT("/users/voice-profile", userHandler.UpdateVoiceProfile)
 			protected.GET("/users/stats", userHandler.GetUserStats)
 			protected.POST("/users/:id/follow", userHandler.FollowUser)
 			protected.DELETE("/users/:id/follow", userHandler.UnfollowUser)
@@ -181,9 +517,32 @@ func main() {
 			protected.POST("/users/reading-list/:blogId", userHandler.AddToReadingList)
 			protected.DELETE("/users/reading-list/:blogId", userHandler.RemoveFromReadingList)
 			protected.GET("/users/reading-list", userHandler.GetReadingList)
+
+			// Admin: prompt templates
+			admin := protected.Group("/admin")
+			admin.Use(middleware.RequireAdmin(adminIDs))
+			{
+				admin.GET("/prompts", promptHandler.ListPrompts)
+				admin.GET("/prompts/:name", promptHandler.GetPrompt)
+				admin.POST("/prompts/:name/versions", promptHandler.CreateVersion)
+				admin.PUT("/prompts/:name/versions/:version", promptHandler.UpdateVersion)
+			}
 		}
 	}
 
+	// Syndication feeds: rss.xml, atom.xml and feed.json
+	feeds := r.Group("/feeds")
+	{
+		feeds.GET("/:file", feedHandler.GetFeed)
+		feeds.GET("/authors/:authorId/:file", feedHandler.GetAuthorFeed)
+		feeds.GET("/tags/:tag/:file", feedHandler.GetTagFeed)
+	}
+
+	// SEO: sitemaps and robots.txt
+	r.GET("/sitemap.xml", sitemapHandler.GetSitemap)
+	r.GET("/sitemaps/:file", sitemapHandler.GetSitemapPage)
+	r.GET("/robots.txt", sitemapHandler.GetRobots)
+
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
@@ -1,19 +1,42 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"ai-blog-backend/internal/models"
 	"ai-blog-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
 )
 
 type AIHandler struct {
-	aiService *services.AIService
+	aiService    *services.AIService
+	usageService *services.UsageService
+	blogService  *services.BlogService
+	jobService   *services.AIJobService
+	seoService   *services.SEOAuditService
+	altService   *services.AltTextService
+	userService  *services.UserService
 }
 
-func NewAIHandler(aiService *services.AIService) *AIHandler {
-	return &AIHandler{aiService: aiService}
+func NewAIHandler(aiService *services.AIService, usageService *services.UsageService, blogService *services.BlogService, jobService *services.AIJobService, seoService *services.SEOAuditService, altService *services.AltTextService, userService *services.UserService) *AIHandler {
+	return &AIHandler{
+		aiService:    aiService,
+		usageService: usageService,
+		blogService:  blogService,
+		jobService:   jobService,
+		seoService:   seoService,
+		altService:   altService,
+		userService:  userService,
+	}
 }
 
 func (h *AIHandler) GenerateContent(c *gin.Context) {
@@ -23,15 +46,75 @@ func (h *AIHandler) GenerateContent(c *gin.Context) {
 		return
 	}
 
-	response, err := h.aiService.GenerateContent(req)
+	userID, ok := h.checkQuota(c)
+	if !ok {
+		return
+	}
+
+	h.applyVoice(userID, &req)
+
+	ctx, recordUsage := h.trackUsage(c, userID, "generate_content")
+	defer recordUsage()
+
+	response, err := h.aiService.GenerateContent(ctx, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondAIError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// GenerateContentStream handles POST /api/ai/generate-content/stream. Content is
+// sent as "content" Server-Sent Events, followed by a final "done" event with the tags.
+func (h *AIHandler) GenerateContentStream(c *gin.Context) {
+	var req services.GenerateContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := h.checkQuota(c)
+	if !ok {
+		return
+	}
+
+	h.applyVoice(userID, &req)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering
+
+	// The request context is cancelled when the client disconnects, which aborts
+	// the upstream AI request. The tokens streamed until then are still recorded.
+	ctx, recordUsage := h.trackUsage(c, userID, "generate_content")
+	defer recordUsage()
+
+	response, err := h.aiService.GenerateContentStream(ctx, req, func(delta string) error {
+		c.SSEvent("content", gin.H{"delta": delta})
+		c.Writer.Flush()
+		return ctx.Err()
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		// The status line has already been sent, so the error only goes in the event
+		_, body := aiErrorResponse(err)
+		c.SSEvent("error", body)
+		c.Writer.Flush()
+		return
+	}
+
+	c.SSEvent("done", gin.H{
+		"tags":           response.Tags,
+		"inputCondensed": response.InputCondensed,
+		"usage":          response.Usage,
+	})
+	c.Writer.Flush()
+}
+
 func (h *AIHandler) GenerateMeta(c *gin.Context) {
 	var req services.GenerateMetaRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -39,11 +122,499 @@ func (h *AIHandler) GenerateMeta(c *gin.Context) {
 		return
 	}
 
-	response, err := h.aiService.GenerateMeta(req)
+	userID, ok := h.checkQuota(c)
+	if !ok {
+		return
+	}
+
+	ctx, recordUsage := h.trackUsage(c, userID, "generate_meta")
+	defer recordUsage()
+
+	response, err := h.aiService.GenerateMeta(ctx, req)
+	if err != nil {
+		respondAIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// TransformText handles POST /api/ai/transform
+func (h *AIHandler) TransformText(c *gin.Context) {
+	var req services.TransformTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := h.checkQuota(c)
+	if !ok {
+		return
+	}
+
+	h.applyVoice(userID, &req)
+
+	ctx, recordUsage := h.trackUsage(c, userID, "transform_text")
+	defer recordUsage()
+
+	response, err := h.aiService.TransformText(ctx, req)
+	if err != nil {
+		respondAIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GenerateOutline handles POST /api/ai/outline
+func (h *AIHandler) GenerateOutline(c *gin.Context) {
+	var req services.GenerateOutlineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := h.checkQuota(c)
+	if !ok {
+		return
+	}
+
+	h.applyVoice(userID, &req)
+
+	ctx, recordUsage := h.trackUsage(c, userID, "generate_outline")
+	defer recordUsage()
+
+	response, err := h.aiService.GenerateOutline(ctx, req)
+	if err != nil {
+		respondAIError(c, err)
+		return
+	}
+
+	// Keep the outline on the draft so it survives reloads
+	if req.BlogID != "" && !h.saveOutline(c, req.BlogID, userID, response.Outline) {
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GenerateFromOutline handles POST /api/ai/generate-from-outline
+func (h *AIHandler) GenerateFromOutline(c *gin.Context) {
+	var req services.GenerateFromOutlineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := h.checkQuota(c)
+	if !ok {
+		return
+	}
+
+	h.applyVoice(userID, &req)
+
+	// Save the edited outline before generating, so edits aren't lost if generation fails
+	if req.BlogID != "" && !h.saveOutline(c, req.BlogID, userID, req.Outline) {
+		return
+	}
+
+	ctx, recordUsage := h.trackUsage(c, userID, "generate_from_outline")
+	defer recordUsage()
+
+	response, err := h.aiService.GenerateFromOutline(h.usageService.WithQuota(ctx, userID), req)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptyOutline) || errors.Is(err, services.ErrOutlineTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var quotaErr *services.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			respondQuotaExceeded(c, quotaErr)
+			return
+		}
+		respondAIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// saveOutline stores the outline on the user's draft, writing an error response and
+// returning false if that fails
+func (h *AIHandler) saveOutline(c *gin.Context, blogID, userID string, outline models.BlogOutline) bool {
+	err := h.blogService.SaveOutline(blogID, userID, outline)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save outline"})
+		return false
+	}
+	return true
+}
+
+// TranslateBlog handles POST /api/blogs/:id/translate?to=es. The translation is
+// saved as a new draft, linked to the original so both can be listed as hreflang alternates.
+func (h *AIHandler) TranslateBlog(c *gin.Context) {
+	locale, err := services.NormalizeLocale(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A target locale is required, e.g. ?to=es"})
+		return
+	}
+
+	userID, ok := h.checkQuota(c)
+	if !ok {
+		return
+	}
+
+	blog, err := h.blogService.GetAuthorBlog(c.Param("id"), userID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if blog.Locale == locale {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Blog is already written in " + locale})
+		return
+	}
+
+	// Check before paying for a translation that can't be saved
+	existing, err := h.blogService.FindTranslation(blog, locale)
+	if err == nil {
+		h.respondTranslationExists(c, existing)
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, recordUsage := h.trackUsage(c, userID, "translate_blog")
+	defer recordUsage()
+
+	response, err := h.aiService.TranslateBlog(h.usageService.WithQuota(ctx, userID), blog, locale)
+	if err != nil {
+		var quotaErr *services.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			respondQuotaExceeded(c, quotaErr)
+			return
+		}
+		respondAIError(c, err)
+		return
+	}
+
+	translation, err := h.blogService.CreateTranslation(blog.ID, userID, locale, response.Translation)
+	if err != nil {
+		if errors.Is(err, services.ErrTranslationExists) {
+			existing, _ := h.blogService.FindTranslation(blog, locale)
+			h.respondTranslationExists(c, existing)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save translation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"blog":  translation,
+		"usage": response.Usage,
+	})
+}
+
+// AuditSEO handles POST /api/blogs/:id/seo-audit. The body is optional.
+func (h *AIHandler) AuditSEO(c *gin.Context) {
+	var req services.SEOAuditRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only the AI suggestions count against the quota
+	var userID string
+	var ok bool
+	if req.SkipSuggestions {
+		if userID, ok = h.getUserID(c); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+	} else if userID, ok = h.checkQuota(c); !ok {
+		return
+	}
+
+	blog, err := h.blogService.GetAuthorBlog(c.Param("id"), userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, recordUsage := h.trackUsage(c, userID, "seo_audit")
+	defer recordUsage()
+
+	response := h.seoService.Audit(ctx, blog, req)
+
 	c.JSON(http.StatusOK, response)
 }
+
+// SuggestAltText handles POST /api/blogs/:id/alt-text
+func (h *AIHandler) SuggestAltText(c *gin.Context) {
+	userID, ok := h.checkQuota(c)
+	if !ok {
+		return
+	}
+
+	blog, err := h.blogService.GetAuthorBlog(c.Param("id"), userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, recordUsage := h.trackUsage(c, userID, "suggest_alt_text")
+	defer recordUsage()
+
+	response, err := h.altService.Suggest(ctx, blog)
+	if err != nil {
+		respondAIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ApplyAltText handles POST /api/blogs/:id/alt-text/apply, writing accepted
+// suggestions into the post's content
+func (h *AIHandler) ApplyAltText(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req services.ApplyAltTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	blog, err := h.altService.Apply(c.Param("id"), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+		case errors.Is(err, services.ErrAltTextConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidSchedule):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blog": blog})
+}
+
+func (h *AIHandler) respondTranslationExists(c *gin.Context, existing *models.Blog) {
+	response := gin.H{"error": services.ErrTranslationExists.Error()}
+	if existing != nil {
+		response["translation"] = gin.H{"id": existing.ID, "slug": existing.Slug, "locale": existing.Locale}
+	}
+	c.JSON(http.StatusConflict, response)
+}
+
+// CreateJob handles POST /api/ai/jobs. The request is queued for a background
+// worker and its job ID returned straight away.
+func (h *AIHandler) CreateJob(c *gin.Context) {
+	var req services.CreateAIJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input, err := services.NewAIJobInput(req.Operation)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := binding.JSON.BindBody(req.Input, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := h.checkQuota(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.jobService.Enqueue(userID, req.Operation, input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue AI job"})
+		return
+	}
+
+	c.Header("Location", "/api/ai/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetJob handles GET /api/ai/jobs/:id
+func (h *AIHandler) GetJob(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	job, err := h.jobService.GetJob(c.Param("id"), userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GetUsage handles GET /api/ai/usage
+func (h *AIHandler) GetUsage(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	summary, err := h.usageService.GetUsage(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get AI usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// Helper function to get the Clerk user ID from context
+func (h *AIHandler) getUserID(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		return "", false
+	}
+
+	userIDStr, ok := userID.(string)
+	return userIDStr, ok
+}
+
+// checkQuota returns the caller's user ID, or writes an error response and returns
+// false if they are not authenticated or have used up their AI quota
+func (h *AIHandler) checkQuota(c *gin.Context) (string, bool) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+
+	if err := h.usageService.CheckQuota(userID); err != nil {
+		var quotaErr *services.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			respondQuotaExceeded(c, quotaErr)
+			return "", false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check AI quota"})
+		return "", false
+	}
+
+	return userID, true
+}
+
+// respondQuotaExceeded writes the response for a user who has used up their AI quota
+func respondQuotaExceeded(c *gin.Context, quotaErr *services.QuotaExceededError) {
+	c.Header("Retry-After", strconv.Itoa(int(time.Until(quotaErr.ResetsAt).Seconds())+1))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":    quotaErr.Error(),
+		"code":     "quota_exceeded",
+		"period":   quotaErr.Period,
+		"limit":    quotaErr.Limit,
+		"used":     quotaErr.Used,
+		"resetsAt": quotaErr.ResetsAt,
+	})
+}
+
+// respondAIError writes the error response for a failed AI request
+func respondAIError(c *gin.Context, err error) {
+	status, body := aiErrorResponse(err)
+	c.JSON(status, body)
+}
+
+// aiErrorResponse returns the status code and body describing a failed AI request
+func aiErrorResponse(err error) (int, gin.H) {
+	var outputErr *services.AIOutputError
+	if errors.As(err, &outputErr) {
+		return http.StatusBadGateway, gin.H{
+			"error":    err.Error(),
+			"code":     "invalid_ai_output",
+			"problems": outputErr.Problems,
+		}
+	}
+
+	if errors.Is(err, services.ErrPromptTooLong) {
+		return http.StatusRequestEntityTooLarge, gin.H{"error": err.Error(), "code": "prompt_too_long"}
+	}
+
+	var aiErr *services.AIError
+	if errors.As(err, &aiErr) {
+		status := http.StatusBadGateway
+		switch aiErr.Kind {
+		case services.AIErrorQuota:
+			status = http.StatusTooManyRequests
+		case services.AIErrorContentFiltered:
+			status = http.StatusUnprocessableEntity
+		case services.AIErrorTimeout:
+			status = http.StatusGatewayTimeout
+		}
+		return status, gin.H{"error": err.Error(), "code": aiErr.Kind}
+	}
+
+	return http.StatusInternalServerError, gin.H{"error": err.Error()}
+}
+
+// applyVoice writes the request in the user's saved voice profile, unless it brings its own
+func (h *AIHandler) applyVoice(userID string, req services.VoiceRequest) {
+	voice, err := h.userService.GetVoiceProfile(userID)
+	if err != nil {
+		log.Printf("Warning: Could not load voice profile for %s: %v", userID, err)
+		return
+	}
+	req.UseDefaultVoice(voice)
+}
+
+// trackUsage returns a context that tracks the AI usage of a request, and a function
+// to defer that records it to the user's ledger. Usage is recorded whether the
+// request succeeds or not, since failed and cancelled calls are still paid for.
+func (h *AIHandler) trackUsage(c *gin.Context, userID, operation string) (context.Context, func()) {
+	ctx, usage := services.TrackUsage(c.Request.Context())
+	return ctx, func() {
+		h.recordUsage(userID, operation, usage.Usage())
+	}
+}
+
+// recordUsage adds a request's token usage to the user's ledger
+func (h *AIHandler) recordUsage(userID, operation string, usage services.TokenUsage) {
+	if usage.TotalTokens == 0 && usage.CacheHits == 0 {
+		return
+	}
+	if err := h.usageService.Record(userID, operation, usage); err != nil {
+		log.Printf("Warning: Could not record AI usage for %s: %v", userID, err)
+	}
+}
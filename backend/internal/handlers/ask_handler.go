@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"ai-blog-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AskHandler struct {
+	askService   *services.AskService
+	usageService *services.UsageService
+	dailyBudget  int // Tokens all readers' questions may spend per day; 0 is unlimited
+}
+
+func NewAskHandler(askService *services.AskService, usageService *services.UsageService, dailyBudget int) *AskHandler {
+	return &AskHandler{askService: askService, usageService: usageService, dailyBudget: dailyBudget}
+}
+
+// Ask answers a reader's question from the published posts, citing its sources
+func (h *AskHandler) Ask(c *gin.Context) {
+	var req services.AskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.usageService.CheckDailyBudget(services.AskUsageAccount, h.dailyBudget); err != nil {
+		var quotaErr *services.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			respondQuotaExceeded(c, quotaErr)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check AI budget"})
+		return
+	}
+
+	// Questions are anonymous, so their usage is recorded against the blog itself
+	ctx, usage := services.TrackUsage(c.Request.Context())
+	defer func() {
+		spent := usage.Usage()
+		if spent.TotalTokens == 0 && spent.CacheHits == 0 {
+			return
+		}
+		if err := h.usageService.Record(services.AskUsageAccount, "ask", spent); err != nil {
+			log.Printf("Warning: Could not record AI usage for %s: %v", services.AskUsageAccount, err)
+		}
+	}()
+
+	response, err := h.askService.Ask(ctx, req)
+	if err != nil {
+		respondAIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -1,24 +1,29 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"ai-blog-backend/internal/models"
 	"ai-blog-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type BlogHandler struct {
-	blogService *services.BlogService
-	aiService   *services.AIService
+	blogService      *services.BlogService
+	aiService        *services.AIService
+	embeddingService *services.EmbeddingService
 }
 
-func NewBlogHandler(blogService *services.BlogService, aiService *services.AIService) *BlogHandler {
+func NewBlogHandler(blogService *services.BlogService, aiService *services.AIService, embeddingService *services.EmbeddingService) *BlogHandler {
 	return &BlogHandler{
-		blogService: blogService,
-		aiService:   aiService,
+		blogService:      blogService,
+		aiService:        aiService,
+		embeddingService: embeddingService,
 	}
 }
 
@@ -51,6 +56,41 @@ func (h *BlogHandler) GetBlogs(c *gin.Context) {
 	})
 }
 
+func (h *BlogHandler) SearchBlogs(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	blogs, total, err := h.blogService.SearchBlogs(query, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"blogs": blogs,
+		"query": query,
+		"pagination": gin.H{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"totalPages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
 func (h *BlogHandler) GetBlog(c *gin.Context) {
 	id := c.Param("id")
 
@@ -68,6 +108,18 @@ func (h *BlogHandler) GetBlogBySlug(c *gin.Context) {
 
 	blog, err := h.blogService.GetBlogBySlug(slug)
 	if err != nil {
+		// The slug may have been retired after a title change
+		if currentSlug, aliasErr := h.blogService.ResolveSlugAlias(slug); aliasErr == nil {
+			location := strings.Replace(c.FullPath(), ":slug", currentSlug, 1)
+			c.Header("Location", location)
+			c.JSON(http.StatusMovedPermanently, gin.H{
+				"error":    "Blog has moved",
+				"redirect": true,
+				"slug":     currentSlug,
+				"location": location,
+			})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
 		return
 	}
@@ -75,6 +127,26 @@ func (h *BlogHandler) GetBlogBySlug(c *gin.Context) {
 	c.JSON(http.StatusOK, blog)
 }
 
+// GetRelatedBlogs handles GET /api/blogs/:id/related, returning the most similar published posts
+func (h *BlogHandler) GetRelatedBlogs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if limit < 1 || limit > 20 {
+		limit = 5
+	}
+
+	blogs, err := h.embeddingService.RelatedBlogs(c.Param("id"), limit)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blogs": blogs})
+}
+
 func (h *BlogHandler) GetUserDrafts(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -138,6 +210,10 @@ func (h *BlogHandler) CreateDraft(c *gin.Context) {
 
 	blog, err := h.blogService.CreateDraft(req, userIDStr, userNameStr, userEmailStr)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidSchedule) || errors.Is(err, services.ErrInvalidLocale) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -195,6 +271,10 @@ func (h *BlogHandler) PublishBlog(c *gin.Context) {
 
 	blog, err := h.blogService.CreateDraft(req, userIDStr, userNameStr, userEmailStr)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidSchedule) || errors.Is(err, services.ErrInvalidLocale) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -225,6 +305,10 @@ func (h *BlogHandler) UpdateBlog(c *gin.Context) {
 
 	blog, err := h.blogService.UpdateBlog(id, req, userIDStr)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidSchedule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
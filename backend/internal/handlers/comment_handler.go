@@ -51,9 +51,14 @@ func (h *CommentHandler) AddComment(c *gin.Context) {
 		return
 	}
 
+	message := "Comment submitted for approval"
+	if comment.Status == "approved" {
+		message = "Comment posted"
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"comment": comment,
-		"message": "Comment submitted for approval",
+		"message": message,
 	})
 }
 
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ai-blog-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedHandler struct {
+	feedService *services.FeedService
+}
+
+func NewFeedHandler(feedService *services.FeedService) *FeedHandler {
+	return &FeedHandler{feedService: feedService}
+}
+
+// GetFeed handles GET /feeds/:file
+func (h *FeedHandler) GetFeed(c *gin.Context) {
+	h.serveFeed(c, services.FeedOptions{})
+}
+
+// GetAuthorFeed handles GET /feeds/authors/:authorId/:file
+func (h *FeedHandler) GetAuthorFeed(c *gin.Context) {
+	h.serveFeed(c, services.FeedOptions{AuthorID: c.Param("authorId")})
+}
+
+// GetTagFeed handles GET /feeds/tags/:tag/:file
+func (h *FeedHandler) GetTagFeed(c *gin.Context) {
+	h.serveFeed(c, services.FeedOptions{Tag: c.Param("tag")})
+}
+
+// serveFeed renders the feed in the format named by the :file parameter
+// (rss.xml, atom.xml or feed.json). Pass ?content=full to include full posts.
+func (h *FeedHandler) serveFeed(c *gin.Context, opts services.FeedOptions) {
+	var render func(*services.Feed) ([]byte, error)
+	var contentType string
+
+	switch c.Param("file") {
+	case "rss.xml":
+		render = h.feedService.RenderRSS
+		contentType = "application/rss+xml; charset=utf-8"
+	case "atom.xml":
+		render = h.feedService.RenderAtom
+		contentType = "application/atom+xml; charset=utf-8"
+	case "feed.json":
+		render = h.feedService.RenderJSON
+		contentType = "application/feed+json; charset=utf-8"
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		return
+	}
+
+	opts.FullContent = c.Query("content") == "full"
+	opts.SelfURL = requestURL(c)
+
+	feed, err := h.feedService.GetFeed(opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+
+	body, err := render(feed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render feed"})
+		return
+	}
+
+	serveConditional(c, contentType, body, feed.Updated)
+}
+
+// serveConditional writes body with ETag and Last-Modified headers, answering
+// 304 Not Modified when the client's cached copy is still current
+func serveConditional(c *gin.Context, contentType string, body []byte, lastModified time.Time) {
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=300")
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+	} else if since := c.GetHeader("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(since); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// requestURL reconstructs the absolute URL the client used for this request
+func requestURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + c.Request.URL.RequestURI()
+}
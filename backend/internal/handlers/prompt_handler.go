@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ai-blog-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PromptHandler struct {
+	promptService *services.PromptService
+}
+
+func NewPromptHandler(promptService *services.PromptService) *PromptHandler {
+	return &PromptHandler{promptService: promptService}
+}
+
+// ListPrompts handles GET /api/admin/prompts
+func (h *PromptHandler) ListPrompts(c *gin.Context) {
+	prompts, err := h.promptService.ListPrompts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prompts": prompts})
+}
+
+// GetPrompt handles GET /api/admin/prompts/:name
+func (h *PromptHandler) GetPrompt(c *gin.Context) {
+	prompt, err := h.promptService.GetPrompt(c.Param("name"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownPrompt) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Prompt not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
+
+// CreateVersion handles POST /api/admin/prompts/:name/versions
+func (h *PromptHandler) CreateVersion(c *gin.Context) {
+	var req services.CreatePromptVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	createdBy, _ := userID.(string)
+
+	version, err := h.promptService.CreateVersion(c.Param("name"), req, createdBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownPrompt):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Prompt not found"})
+		case errors.Is(err, services.ErrInvalidPromptTemplate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, version)
+}
+
+// UpdateVersion handles PUT /api/admin/prompts/:name/versions/:version, which
+// changes how often the version is used
+func (h *PromptHandler) UpdateVersion(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version number"})
+		return
+	}
+
+	var req services.UpdatePromptVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := h.promptService.SetWeight(c.Param("name"), number, *req.Weight)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Prompt version not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ai-blog-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RevisionHandler struct {
+	revisionService *services.RevisionService
+}
+
+func NewRevisionHandler(revisionService *services.RevisionService) *RevisionHandler {
+	return &RevisionHandler{revisionService: revisionService}
+}
+
+// Helper function to get the author ID from context
+func (h *RevisionHandler) getAuthorID(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		return "", false
+	}
+
+	userIDStr, ok := userID.(string)
+	return userIDStr, ok
+}
+
+// ListRevisions handles GET /api/blogs/:id/revisions
+func (h *RevisionHandler) ListRevisions(c *gin.Context) {
+	authorID, ok := h.getAuthorID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	revisions, err := h.revisionService.ListRevisions(c.Param("id"), authorID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// GetRevision handles GET /api/blogs/:id/revisions/:revision
+func (h *RevisionHandler) GetRevision(c *gin.Context) {
+	authorID, ok := h.getAuthorID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	rev, err := h.revisionService.GetRevision(c.Param("id"), revision, authorID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+// DiffRevisions handles GET /api/blogs/:id/revisions/diff?from=1&to=2
+func (h *RevisionHandler) DiffRevisions(c *gin.Context) {
+	authorID, ok := h.getAuthorID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' revision number"})
+		return
+	}
+
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' revision number"})
+		return
+	}
+
+	diff, err := h.revisionService.DiffRevisions(c.Param("id"), from, to, authorID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// RestoreRevision handles POST /api/blogs/:id/revisions/:revision/restore
+func (h *RevisionHandler) RestoreRevision(c *gin.Context) {
+	authorID, ok := h.getAuthorID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	blog, err := h.revisionService.RestoreRevision(c.Param("id"), revision, authorID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return
+		}
+		// A scheduled blog whose publish time has passed but hasn't been published yet
+		if errors.Is(err, services.ErrInvalidSchedule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, blog)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-blog-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SitemapHandler struct {
+	sitemapService *services.SitemapService
+}
+
+func NewSitemapHandler(sitemapService *services.SitemapService) *SitemapHandler {
+	return &SitemapHandler{sitemapService: sitemapService}
+}
+
+const sitemapContentType = "application/xml; charset=utf-8"
+
+// GetSitemap handles GET /sitemap.xml. It serves a single sitemap, or a
+// sitemap index once there are more URLs than fit in one file.
+func (h *SitemapHandler) GetSitemap(c *gin.Context) {
+	pages, err := h.sitemapService.GetIndexPages()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sitemap"})
+		return
+	}
+
+	if pages != nil {
+		body, err := h.sitemapService.RenderIndex(pages)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render sitemap"})
+			return
+		}
+
+		var lastMod time.Time
+		for _, pageLastMod := range pages {
+			if pageLastMod.After(lastMod) {
+				lastMod = pageLastMod
+			}
+		}
+		serveConditional(c, sitemapContentType, body, lastMod)
+		return
+	}
+
+	entries, err := h.sitemapService.GetEntries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sitemap"})
+		return
+	}
+
+	body, err := h.sitemapService.RenderURLSet(entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render sitemap"})
+		return
+	}
+
+	serveConditional(c, sitemapContentType, body, services.LatestModification(entries))
+}
+
+// GetSitemapPage handles GET /sitemaps/:file, where file is e.g. 2.xml
+func (h *SitemapHandler) GetSitemapPage(c *gin.Context) {
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("file"), ".xml"))
+	if err != nil || !strings.HasSuffix(c.Param("file"), ".xml") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sitemap not found"})
+		return
+	}
+
+	pageEntries, err := h.sitemapService.GetEntriesPage(page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sitemap"})
+		return
+	}
+	if len(pageEntries) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sitemap not found"})
+		return
+	}
+
+	body, err := h.sitemapService.RenderURLSet(pageEntries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render sitemap"})
+		return
+	}
+
+	serveConditional(c, sitemapContentType, body, services.LatestModification(pageEntries))
+}
+
+// GetRobots handles GET /robots.txt
+func (h *SitemapHandler) GetRobots(c *gin.Context) {
+	body := h.sitemapService.RenderRobots()
+	c.String(http.StatusOK, body)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"ai-blog-backend/internal/models"
 	"ai-blog-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -107,6 +108,61 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, profile)
 }
 
+// GetVoiceProfile handles GET /api/users/voice-profile
+func (h *UserHandler) GetVoiceProfile(c *gin.Context) {
+	clerkUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	clerkUserIDStr, ok := clerkUserID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	voice, err := h.userService.GetVoiceProfile(clerkUserIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get voice profile"})
+		return
+	}
+	if voice == nil {
+		voice = &models.VoiceProfile{}
+	}
+
+	c.JSON(http.StatusOK, voice)
+}
+
+// UpdateVoiceProfile handles PUT /api/users/voice-profile
+func (h *UserHandler) UpdateVoiceProfile(c *gin.Context) {
+	clerkUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	clerkUserIDStr, ok := clerkUserID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.VoiceProfile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	voice, err := h.userService.UpdateVoiceProfile(clerkUserIDStr, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update voice profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, voice)
+}
+
 // GetUserStats handles GET /api/users/stats
 func (h *UserHandler) GetUserStats(c *gin.Context) {
 	clerkUserID, exists := c.Get("userID")
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin only lets through users whose Clerk ID is in adminIDs.
+// It must run after ClerkAuth.
+func RequireAdmin(adminIDs []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+
+	return func(c *gin.Context) {
+		userID, _ := c.Get("userID")
+		id, ok := userID.(string)
+		if !ok || !admins[id] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
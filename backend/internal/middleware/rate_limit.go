@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit lets each client IP make at most limit requests per window and answers
+// the rest with 429. Counts are kept in memory, so each instance limits separately.
+// A limit of 0 or less disables it.
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	windowStart := time.Now()
+
+	return func(c *gin.Context) {
+		mu.Lock()
+		now := time.Now()
+		// Starting a new window also forgets clients that have gone quiet
+		if now.Sub(windowStart) >= window {
+			counts = make(map[string]int)
+			windowStart = now
+		}
+		ip := c.ClientIP()
+		counts[ip]++
+		allowed := counts[ip] <= limit
+		resetsIn := window - now.Sub(windowStart)
+		mu.Unlock()
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(resetsIn.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AIJob is an AI request queued for a background worker
+type AIJob struct {
+	ID          string          `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	ClerkUserID string          `json:"-" gorm:"not null;index"`
+	Operation   string          `json:"operation" gorm:"not null"`                                               // generate_content, generate_meta, etc.
+	Status      string          `json:"status" gorm:"not null;default:'queued';index:idx_ai_jobs_status_run_at"` // queued, running, succeeded, failed
+	Input       json.RawMessage `json:"-" gorm:"type:jsonb"`
+	Result      json.RawMessage `json:"result,omitempty" gorm:"type:jsonb"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int             `json:"attempts" gorm:"default:0"`
+	RunAt       time.Time       `json:"runAt" gorm:"index:idx_ai_jobs_status_run_at"` // Earliest time the next attempt may start
+	StartedAt   *time.Time      `json:"startedAt"`
+	FinishedAt  *time.Time      `json:"finishedAt"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+func (j *AIJob) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == "" {
+		j.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// AIResponseCache is a cached AI response, keyed by a hash of the model, prompt
+// template versions and prompt that produced it
+type AIResponseCache struct {
+	Key              string `gorm:"primaryKey;size:64"`
+	Model            string `gorm:"not null"`
+	Content          string `gorm:"type:text;not null"`
+	PromptTokens     int    // Tokens the original call cost
+	CompletionTokens int
+	ExpiresAt        time.Time `gorm:"not null;index"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
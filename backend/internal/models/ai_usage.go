@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// AIUsage is a ledger entry recording the tokens spent by one AI request
+type AIUsage struct {
+	ID               string         `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	ClerkUserID      string         `json:"clerkUserId" gorm:"not null;index:idx_ai_usages_user_created"`
+	Operation        string         `json:"operation" gorm:"not null"` // generate_content, generate_meta, etc.
+	Model            string         `json:"model"`
+	PromptTokens     int            `json:"promptTokens"`
+	CompletionTokens int            `json:"completionTokens"`
+	TotalTokens      int            `json:"totalTokens"`
+	CacheHits        int            `json:"cacheHits"`                  // Model calls answered from the response cache
+	Prompts          pq.StringArray `json:"prompts" gorm:"type:text[]"` // Prompt template versions, e.g. generate_meta@v3
+	CreatedAt        time.Time      `json:"createdAt" gorm:"index:idx_ai_usages_user_created"`
+}
+
+func (u *AIUsage) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == "" {
+		u.ID = uuid.New().String()
+	}
+	return nil
+}
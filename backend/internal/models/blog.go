@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,26 +12,35 @@ import (
 )
 
 type Blog struct {
-	ID              string         `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
-	Title           string         `json:"title" gorm:"not null"`
-	Content         string         `json:"content" gorm:"type:text"`
-	Excerpt         string         `json:"excerpt"`
-	Slug            string         `json:"slug" gorm:"uniqueIndex;not null"`
-	AuthorID        string         `json:"authorId" gorm:"not null"`
-	AuthorName      string         `json:"authorName"`
-	AuthorEmail     string         `json:"authorEmail"`
-	Status          string         `json:"status" gorm:"default:'draft'"` // draft, published, archived
-	Tags            pq.StringArray `json:"tags" gorm:"type:text[]"`
-	MetaTitle       string         `json:"metaTitle"`
-	MetaDescription string         `json:"metaDescription"`
-	FeaturedImage   string         `json:"featuredImage"`
-	ViewCount       int            `json:"viewCount" gorm:"default:0"`
-	LikeCount       int            `json:"likeCount" gorm:"default:0"`
-	ShareCount      int            `json:"shareCount" gorm:"default:0"`
-	PublishedAt     *time.Time     `json:"publishedAt"`
-	CreatedAt       time.Time      `json:"createdAt"`
-	UpdatedAt       time.Time      `json:"updatedAt"`
-	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 string            `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	Title              string            `json:"title" gorm:"not null"`
+	Content            string            `json:"content" gorm:"type:text"`
+	Excerpt            string            `json:"excerpt"`
+	Slug               string            `json:"slug" gorm:"uniqueIndex;not null"`
+	AuthorID           string            `json:"authorId" gorm:"not null"`
+	AuthorName         string            `json:"authorName"`
+	AuthorEmail        string            `json:"authorEmail"`
+	Status             string            `json:"status" gorm:"default:'draft'"` // draft, scheduled, published, archived
+	Tags               pq.StringArray    `json:"tags" gorm:"type:text[]"`
+	MetaTitle          string            `json:"metaTitle"`
+	MetaDescription    string            `json:"metaDescription"`
+	FeaturedImage      string            `json:"featuredImage"`
+	ViewCount          int               `json:"viewCount" gorm:"default:0"`
+	LikeCount          int               `json:"likeCount" gorm:"default:0"`
+	ShareCount         int               `json:"shareCount" gorm:"default:0"`
+	PublishedAt        *time.Time        `json:"publishedAt"`
+	ScheduledAt        *time.Time        `json:"scheduledAt"` // When a scheduled blog should be published
+	Outline            *BlogOutline      `json:"outline,omitempty" gorm:"type:jsonb"`
+	Locale             string            `json:"locale" gorm:"default:'en'"`          // BCP 47 language tag, e.g. en, es, pt-BR
+	TranslationGroupID *string           `json:"translationGroupId" gorm:"type:uuid"` // ID of the original post, shared by its translations
+	Translations       []BlogTranslation `json:"translations,omitempty" gorm:"-"`     // Published siblings, for hreflang links
+	Summary            string            `json:"summary" gorm:"type:text"`            // TL;DR, kept up to date by SummaryService
+	KeyTakeaways       pq.StringArray    `json:"keyTakeaways" gorm:"type:text[]"`     // 3 to 5 short bullet points
+	SummarySource      string            `json:"summarySource"`                       // ai or extractive
+	SummaryFingerprint string            `json:"-"`                                   // Simhash of the text the summary was made from
+	CreatedAt          time.Time         `json:"createdAt"`
+	UpdatedAt          time.Time         `json:"updatedAt"`
+	DeletedAt          gorm.DeletedAt    `json:"-" gorm:"index"`
 }
 
 func (b *Blog) BeforeCreate(tx *gorm.DB) error {
@@ -39,12 +51,65 @@ func (b *Blog) BeforeCreate(tx *gorm.DB) error {
 }
 
 type CreateBlogRequest struct {
-	Title           string   `json:"title" binding:"required"`
-	Content         string   `json:"content" binding:"required"`
-	Description     string   `json:"description"`
-	Tags            []string `json:"tags"`
-	Status          string   `json:"status" binding:"required,oneof=draft published"`
-	MetaTitle       string   `json:"metaTitle"`
-	MetaDescription string   `json:"metaDescription"`
-	FeaturedImage   string   `json:"featuredImage"`
+	Title           string       `json:"title" binding:"required"`
+	Content         string       `json:"content" binding:"required"`
+	Description     string       `json:"description"`
+	Tags            []string     `json:"tags"`
+	Status          string       `json:"status" binding:"required,oneof=draft published scheduled"`
+	ScheduledAt     *time.Time   `json:"scheduledAt"` // Required when status is scheduled
+	Outline         *BlogOutline `json:"outline"`     // Left unchanged on update when omitted
+	Locale          string       `json:"locale"`      // Defaults to en; only used when creating
+	MetaTitle       string       `json:"metaTitle"`
+	MetaDescription string       `json:"metaDescription"`
+	FeaturedImage   string       `json:"featuredImage"`
+}
+
+// BlogSearchResult is a published blog matched by full-text search, along with
+// its rank and highlighted fragments of the matching text.
+type BlogSearchResult struct {
+	Blog
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"titleHighlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+// RelatedBlog is a published blog similar to another one, with the cosine
+// similarity of their embeddings
+type RelatedBlog struct {
+	Blog
+	Similarity float64 `json:"similarity"`
+}
+
+// BlogTranslation links a post to one of its published translations
+type BlogTranslation struct {
+	Locale string `json:"locale"`
+	Slug   string `json:"slug"`
+	Title  string `json:"title"`
+}
+
+// BlogOutline is the editable structure of a post, generated before its content
+type BlogOutline struct {
+	Sections []OutlineSection `json:"sections" binding:"max=12"` // Matches services.maxOutlineSections
+}
+
+type OutlineSection struct {
+	Heading   string   `json:"heading"`
+	KeyPoints []string `json:"keyPoints"`
+}
+
+// Value stores the outline as JSON
+func (o BlogOutline) Value() (driver.Value, error) {
+	return json.Marshal(o)
+}
+
+// Scan reads the outline from a JSON column
+func (o *BlogOutline) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	default:
+		return errors.New("unsupported type for BlogOutline")
+	}
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// BlogEmbedding is the semantic vector of a published blog, used to find related posts
+type BlogEmbedding struct {
+	BlogID      string          `json:"blogId" gorm:"primaryKey;type:uuid"`
+	Model       string          `json:"model" gorm:"not null"`
+	Embedding   pq.Float64Array `json:"-" gorm:"type:float8[]"` // Unit length
+	ContentHash string          `json:"-"`                      // Skips re-embedding unchanged posts
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
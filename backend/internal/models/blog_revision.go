@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// BlogRevision is a snapshot of a blog taken every time it is saved
+type BlogRevision struct {
+	ID              string         `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	BlogID          string         `json:"blogId" gorm:"not null;uniqueIndex:idx_blog_revisions_blog_revision"`
+	Revision        int            `json:"revision" gorm:"not null;uniqueIndex:idx_blog_revisions_blog_revision"`
+	AuthorID        string         `json:"authorId" gorm:"not null;index"`
+	Title           string         `json:"title"`
+	Content         string         `json:"content" gorm:"type:text"`
+	Excerpt         string         `json:"excerpt"`
+	Slug            string         `json:"slug"`
+	Status          string         `json:"status"`
+	Tags            pq.StringArray `json:"tags" gorm:"type:text[]"`
+	MetaTitle       string         `json:"metaTitle"`
+	MetaDescription string         `json:"metaDescription"`
+	FeaturedImage   string         `json:"featuredImage"`
+	CreatedAt       time.Time      `json:"createdAt"`
+}
+
+func (r *BlogRevision) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -4,25 +4,29 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
 type Comment struct {
-	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
-	Content     string    `json:"content" gorm:"not null"`
-	AuthorName  string    `json:"authorName" gorm:"not null"`
-	AuthorEmail string    `json:"authorEmail" gorm:"not null"`
-	BlogID      string    `json:"blogId" gorm:"not null"`
-	Status      string    `json:"status" gorm:"default:'pending'"` // pending, approved, rejected
-	ParentID    *string   `json:"parentId"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
-	
+	ID                string         `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	Content           string         `json:"content" gorm:"not null"`
+	AuthorName        string         `json:"authorName" gorm:"not null"`
+	AuthorEmail       string         `json:"authorEmail" gorm:"not null"`
+	BlogID            string         `json:"blogId" gorm:"not null"`
+	Status            string         `json:"status" gorm:"default:'pending'"` // pending, approved, rejected
+	ParentID          *string        `json:"parentId"`
+	ModerationScore   *float64       `json:"moderationScore"` // 0 (clean) to 1 (unacceptable); nil if not moderated
+	ModerationReasons pq.StringArray `json:"moderationReasons" gorm:"type:text[]"`
+	ModeratedBy       string         `json:"moderatedBy"` // Classifier that scored the comment: ai or heuristic
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+
 	// Relationships
-	Blog     Blog       `json:"blog" gorm:"foreignKey:BlogID"`
-	Parent   *Comment   `json:"parent" gorm:"foreignKey:ParentID"`
-	Replies  []Comment  `json:"replies" gorm:"foreignKey:ParentID"`
+	Blog    Blog      `json:"blog" gorm:"foreignKey:BlogID"`
+	Parent  *Comment  `json:"parent" gorm:"foreignKey:ParentID"`
+	Replies []Comment `json:"replies" gorm:"foreignKey:ParentID"`
 }
 
 func (c *Comment) BeforeCreate(tx *gorm.DB) error {
@@ -37,4 +41,4 @@ type CreateCommentRequest struct {
 	AuthorName  string `json:"authorName" binding:"required"`
 	AuthorEmail string `json:"authorEmail" binding:"required,email"`
 	ParentID    string `json:"parentId"`
-} 
\ No newline at end of file
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PromptTemplate is one version of the prompt for an AI operation, written in Go
+// text/template syntax. Versions are never edited; changing a prompt adds a version.
+type PromptTemplate struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_prompt_templates_name_version"` // generate_content, generate_meta or generate_tags
+	Version   int       `json:"version" gorm:"not null;uniqueIndex:idx_prompt_templates_name_version"`
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	Weight    int       `json:"weight" gorm:"not null;default:0"` // Share of requests among active versions; 0 turns the version off
+	Notes     string    `json:"notes"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (p *PromptTemplate) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SlugAlias records a slug a blog used to have, so old links can be redirected.
+// Aliases are hard-deleted when a blog takes its old slug back, so there is no DeletedAt.
+type SlugAlias struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	Slug      string    `json:"slug" gorm:"uniqueIndex;not null"`
+	BlogID    string    `json:"blogId" gorm:"not null;index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (a *SlugAlias) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
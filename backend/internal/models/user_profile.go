@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,6 +26,7 @@ type UserProfile struct {
 	FollowerCount   int            `json:"followerCount" gorm:"default:0"`
 	FollowingCount  int            `json:"followingCount" gorm:"default:0"`
 	IsVerified      bool           `json:"isVerified" gorm:"default:false"`
+	VoiceProfile    *VoiceProfile  `json:"voiceProfile,omitempty" gorm:"type:jsonb"` // Writing style applied to AI generation
 	JoinedAt        time.Time      `json:"joinedAt"`
 	LastActiveAt    *time.Time     `json:"lastActiveAt"`
 	CreatedAt       time.Time      `json:"createdAt"`
@@ -40,6 +44,36 @@ func (u *UserProfile) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// VoiceProfile describes how an author writes, so AI-generated text sounds like them
+type VoiceProfile struct {
+	Samples      []string `json:"samples" binding:"max=3,dive,max=2000"`    // Paragraphs written by the author
+	BannedWords  []string `json:"bannedWords" binding:"max=50,dive,max=50"` // Words and phrases never to use
+	ReadingLevel int      `json:"readingLevel" binding:"min=0,max=18"`      // School grade to write for; 0 for no preference
+	StyleRules   []string `json:"styleRules" binding:"max=20,dive,max=200"` // House style, e.g. "Use British spelling"
+}
+
+// IsEmpty reports whether the profile has nothing to apply
+func (v *VoiceProfile) IsEmpty() bool {
+	return v == nil || (len(v.Samples) == 0 && len(v.BannedWords) == 0 && v.ReadingLevel == 0 && len(v.StyleRules) == 0)
+}
+
+// Value stores the voice profile as JSON
+func (v VoiceProfile) Value() (driver.Value, error) {
+	return json.Marshal(v)
+}
+
+// Scan reads the voice profile from a JSON column
+func (v *VoiceProfile) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case []byte:
+		return json.Unmarshal(data, v)
+	case string:
+		return json.Unmarshal([]byte(data), v)
+	default:
+		return errors.New("unsupported type for VoiceProfile")
+	}
+}
+
 // UserFollow represents the follow relationship between users
 type UserFollow struct {
 	ID          string         `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
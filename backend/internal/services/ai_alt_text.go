@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SuggestAltText writes alt text for each image from the text around it, and a
+// prompt for generating the post's featured image
+func (s *AIService) SuggestAltText(ctx context.Context, title, excerpt string, images []MissingAltImage) (*AltTextSuggestionsResponse, error) {
+	ctx, cancel := s.withTimeout(ctx, "suggest_alt_text")
+	defer cancel()
+	ctx, usage := TrackUsage(ctx)
+
+	var list strings.Builder
+	for i, image := range images {
+		fmt.Fprintf(&list, "[%d] File: %s\nSection: %s\nSurrounding text: %s\n\n", i+1, image.Src, image.Heading, image.Context)
+	}
+	if len(images) == 0 {
+		list.WriteString("(none)\n\n")
+	}
+
+	prompt := fmt.Sprintf(`You are helping make a blog post accessible. For each numbered image below, write alt text describing what the image most likely shows, based on its file name and the text around it.
+
+Alt text rules:
+- At most %d characters, one sentence, no trailing period
+- Describe the image itself; don't start with "Image of" or "Picture of"
+- Write in the same language as the surrounding text
+
+Also write a prompt for an image generator that would produce a fitting featured image for the post: a concrete scene in one or two sentences, with no text in the image.
+
+Post title: %s
+Post summary: %s
+
+Images:
+%s
+Respond with a JSON object in exactly this shape, with one alt text per image in order:
+{"altTexts": ["...", "..."], "featuredImagePrompt": "..."}`, maxAltTextLength, title, excerpt, list.String())
+
+	var output struct {
+		AltTexts            []string `json:"altTexts"`
+		FeaturedImagePrompt string   `json:"featuredImagePrompt"`
+	}
+	err := s.generateJSON(ctx, "suggest alt text", prompt, 100+60*len(images), &output, func() []string {
+		output.FeaturedImagePrompt = strings.TrimSpace(output.FeaturedImagePrompt)
+
+		var problems []string
+		if len(output.AltTexts) != len(images) {
+			problems = append(problems, fmt.Sprintf("altTexts must have %d items, one per image, but it has %d", len(images), len(output.AltTexts)))
+		}
+		for i := range output.AltTexts {
+			output.AltTexts[i] = strings.TrimSuffix(strings.TrimSpace(output.AltTexts[i]), ".")
+			if length := utf8.RuneCountInString(output.AltTexts[i]); length == 0 || length > maxAltTextLength {
+				problems = append(problems, fmt.Sprintf("alt text %d must be 1-%d characters long, but it is %d characters", i+1, maxAltTextLength, length))
+			}
+		}
+		if output.FeaturedImagePrompt == "" {
+			problems = append(problems, "featuredImagePrompt must not be empty")
+		}
+		return problems
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest alt text: %w", err)
+	}
+
+	suggestions := make([]AltTextSuggestion, len(images))
+	for i, image := range images {
+		suggestions[i] = AltTextSuggestion{Index: image.Index, Src: image.Src, Alt: output.AltTexts[i]}
+	}
+
+	return &AltTextSuggestionsResponse{
+		Images:              suggestions,
+		FeaturedImagePrompt: output.FeaturedImagePrompt,
+		Usage:               usage.Usage(),
+	}, nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// AnswerQuestion answers a reader's question using only the given passages,
+// citing the passages it relied on
+func (s *AIService) AnswerQuestion(ctx context.Context, question string, passages []AskPassage) (*AskResponse, error) {
+	ctx, cancel := s.withTimeout(ctx, "answer_question")
+	defer cancel()
+	ctx, usage := TrackUsage(ctx)
+
+	var sources strings.Builder
+	for i, passage := range passages {
+		fmt.Fprintf(&sources, "[%d] %s (section: %s)\n%s\n\n", i+1, passage.Title, passage.Heading, passage.Text)
+	}
+
+	prompt := fmt.Sprintf(`Answer a reader's question about our blog using only the numbered sources below, which are passages from our posts.
+
+Rules:
+- Use only facts stated in the sources. Do not add outside knowledge.
+- If the sources don't answer the question, set "answered" to false and leave "sources" empty.
+- Otherwise answer in a few sentences and list the numbers of every source you used.
+
+Sources:
+%s
+Question: %s
+
+Respond with a JSON object in exactly this shape:
+{"answer": "...", "answered": true, "sources": [1, 2]}`, sources.String(), question)
+
+	var output struct {
+		Answer   string `json:"answer"`
+		Answered bool   `json:"answered"`
+		Sources  []int  `json:"sources"`
+	}
+	err := s.generateJSON(ctx, "answer question", prompt, 500, &output, func() []string {
+		output.Answer = strings.TrimSpace(output.Answer)
+
+		var problems []string
+		for _, n := range output.Sources {
+			if n < 1 || n > len(passages) {
+				problems = append(problems, fmt.Sprintf("source %d does not exist, sources are numbered 1 to %d", n, len(passages)))
+			}
+		}
+		if output.Answered && output.Answer == "" {
+			problems = append(problems, "answer must not be empty when answered is true")
+		}
+		if output.Answered && len(output.Sources) == 0 {
+			problems = append(problems, "sources must list at least one source when answered is true")
+		}
+		return problems
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to answer question: %w", err)
+	}
+
+	if !output.Answered {
+		response := refusal()
+		response.Usage = usage.Usage()
+		return response, nil
+	}
+
+	citations := []AskCitation{}
+	cited := make(map[int]bool)
+	for _, n := range output.Sources {
+		if cited[n] {
+			continue
+		}
+		cited[n] = true
+		passage := passages[n-1]
+		citations = append(citations, AskCitation{Slug: passage.Slug, Title: passage.Title, Anchor: passage.Anchor})
+	}
+
+	return &AskResponse{
+		Answer:    output.Answer,
+		Answered:  true,
+		Citations: citations,
+		Usage:     usage.Usage(),
+	}, nil
+}
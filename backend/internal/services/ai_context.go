@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// defaultContextWindow is assumed for models we don't know, such as self-hosted ones
+const defaultContextWindow = 4096
+
+// modelContextWindows lists context window sizes in tokens by model name prefix,
+// most specific first
+var modelContextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-1106", 128000},
+	{"gpt-4-0125", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo-instruct", 4096},
+	{"gpt-3.5-turbo", 16385},
+}
+
+// maxCompletionTokens is the longest response requested in one call; many models
+// can't generate more than this whatever their context window
+const maxCompletionTokens = 4096
+
+// minCompletionTokens is the least room a prompt must leave for the response
+const minCompletionTokens = 64
+
+// ErrPromptTooLong is returned for requests that don't fit the model's context window
+var ErrPromptTooLong = errors.New("the text is too long for the AI model")
+
+// maxCondenseRounds limits how many times oversized content is summarised before
+// it is truncated instead
+const maxCondenseRounds = 2
+
+// contextWindow returns the number of tokens the model can handle in one call,
+// prompt and completion combined
+func contextWindow(model string) int {
+	for _, window := range modelContextWindows {
+		if strings.HasPrefix(model, window.prefix) {
+			return window.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+// fitRequest caps a request's completion to what the model can generate beside
+// its prompt, returning ErrPromptTooLong if the prompt leaves too little room
+func fitRequest(model string, req TextGenerationRequest) (TextGenerationRequest, error) {
+	promptTokens := 0
+	for _, msg := range req.Messages {
+		promptTokens += countTokens(model, msg.Content) + 4 // Role and message framing
+	}
+
+	// Keep a twentieth of the window spare, since token counts may be estimates
+	window := contextWindow(model)
+	available := window - window/20 - promptTokens
+	if available < minCompletionTokens {
+		return req, fmt.Errorf("%w: about %d tokens of a %d token context window", ErrPromptTooLong, promptTokens, window)
+	}
+	if available > maxCompletionTokens {
+		available = maxCompletionTokens
+	}
+	if req.MaxTokens == 0 || req.MaxTokens > available {
+		req.MaxTokens = available
+	}
+	return req, nil
+}
+
+// jsonCallTokens is the room a generateJSON call needs besides its prompt: the
+// completion plus the responses and feedback of any retries
+func jsonCallTokens(maxTokens int) int {
+	return maxJSONAttempts * (maxTokens + 100)
+}
+
+// fitContent returns content unchanged if it fits in the model's context window
+// alongside reservedTokens of prompt and completion. Otherwise it is condensed by
+// summarising it in chunks, and the second return value is true.
+func (s *AIService) fitContent(ctx context.Context, content string, reservedTokens int) (string, bool, error) {
+	model := s.generator.Model()
+	window := contextWindow(model)
+
+	// Keep a tenth of the window spare, since token counts may be estimates
+	budget := window - window/10 - reservedTokens
+	if budget < 256 {
+		budget = 256
+	}
+
+	if countTokens(model, content) <= budget {
+		return content, false, nil
+	}
+
+	for round := 0; countTokens(model, content) > budget; round++ {
+		if round == maxCondenseRounds {
+			return truncateTokens(model, content, budget), true, nil
+		}
+
+		chunks := splitChunks(model, content, window/2)
+		summaryTokens := budget / len(chunks)
+		if summaryTokens > window/4 {
+			summaryTokens = window / 4
+		}
+		if summaryTokens < 64 {
+			summaryTokens = 64
+		}
+
+		summaries := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			summary, err := s.summarizeChunk(ctx, chunk, summaryTokens)
+			if err != nil {
+				return "", false, fmt.Errorf("failed to condense content: %w", err)
+			}
+			summaries[i] = summary
+		}
+		content = strings.Join(summaries, "\n\n")
+	}
+
+	return content, true, nil
+}
+
+// summarizeChunk condenses one part of a post to about maxTokens tokens
+func (s *AIService) summarizeChunk(ctx context.Context, chunk string, maxTokens int) (string, error) {
+	prompt := fmt.Sprintf(`Summarise the following part of a blog post in at most %d words.
+Keep its main topics, key terms, names and conclusions. Respond with the summary only.
+
+"""
+%s
+"""`, maxTokens*3/4, chunk)
+
+	resp, err := s.complete(ctx, prompt, maxTokens)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// splitChunks splits text into chunks of at most maxTokens tokens for model,
+// breaking between paragraphs where possible
+func splitChunks(model, text string, maxTokens int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentTokens = 0
+		}
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		tokens := countTokens(model, paragraph)
+		// Paragraphs too long for a chunk of their own are cut
+		if tokens > maxTokens {
+			flush()
+			pieces := cutTokens(model, paragraph, maxTokens)
+			chunks = append(chunks, pieces[:len(pieces)-1]...)
+			paragraph = pieces[len(pieces)-1]
+			tokens = countTokens(model, paragraph)
+		}
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+
+		// The blank line between paragraphs is about one token
+		if current.Len() > 0 && currentTokens+1+tokens > maxTokens {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+			currentTokens++
+		}
+		current.WriteString(paragraph)
+		currentTokens += tokens
+	}
+	flush()
+
+	if len(chunks) == 0 {
+		chunks = []string{text}
+	}
+	return chunks
+}
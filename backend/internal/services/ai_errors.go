@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Kinds of AIError
+const (
+	AIErrorQuota           = "quota"            // The provider rate limited the request or the account is out of credit
+	AIErrorContentFiltered = "content_filtered" // The provider's safety filter blocked the prompt or the response
+	AIErrorTimeout         = "timeout"          // The operation ran out of time
+	AIErrorUpstream        = "upstream"         // The provider failed or returned an unusable response
+)
+
+// Errors matched by AIErrors of each kind
+var (
+	ErrAIQuota           = errors.New("AI provider quota exceeded")
+	ErrAIContentFiltered = errors.New("AI provider blocked the content")
+	ErrAITimeout         = errors.New("AI request timed out")
+	ErrAIUpstream        = errors.New("AI provider request failed")
+)
+
+var aiErrorKinds = map[string]error{
+	AIErrorQuota:           ErrAIQuota,
+	AIErrorContentFiltered: ErrAIContentFiltered,
+	AIErrorTimeout:         ErrAITimeout,
+	AIErrorUpstream:        ErrAIUpstream,
+}
+
+// AIError is a failed call to the AI provider. It matches the Err* value of its
+// kind with errors.Is, and unwraps to the provider's own error.
+type AIError struct {
+	Kind       string
+	StatusCode int  // HTTP status returned by the provider, 0 if there was no response
+	Retryable  bool // The same request may succeed if tried again
+	Err        error
+}
+
+func (e *AIError) Error() string {
+	return fmt.Sprintf("%v: %v", aiErrorKinds[e.Kind], e.Err)
+}
+
+func (e *AIError) Unwrap() error {
+	return e.Err
+}
+
+func (e *AIError) Is(target error) bool {
+	return target == aiErrorKinds[e.Kind]
+}
+
+// classifyAIError turns an error from a model call into an *AIError. Errors it
+// doesn't recognise, including cancellation by the caller, are returned unchanged.
+func classifyAIError(ctx context.Context, err error) error {
+	var aiErr *AIError
+	if err == nil || errors.As(err, &aiErr) {
+		return err
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &AIError{Kind: AIErrorTimeout, Retryable: true, Err: err}
+	}
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		code, _ := apiErr.Code.(string)
+		switch {
+		case code == "content_filter" || code == "content_policy_violation":
+			return &AIError{Kind: AIErrorContentFiltered, StatusCode: apiErr.HTTPStatusCode, Err: err}
+		case apiErr.HTTPStatusCode == http.StatusTooManyRequests:
+			// Running out of credit doesn't fix itself by waiting
+			return &AIError{Kind: AIErrorQuota, StatusCode: apiErr.HTTPStatusCode, Retryable: code != "insufficient_quota", Err: err}
+		default:
+			return upstreamError(apiErr.HTTPStatusCode, err)
+		}
+	}
+
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		if reqErr.HTTPStatusCode == http.StatusTooManyRequests {
+			return &AIError{Kind: AIErrorQuota, StatusCode: reqErr.HTTPStatusCode, Retryable: true, Err: err}
+		}
+		return upstreamError(reqErr.HTTPStatusCode, err)
+	}
+
+	// Dropped connections and network timeouts
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return &AIError{Kind: AIErrorTimeout, Retryable: true, Err: err}
+		}
+		return &AIError{Kind: AIErrorUpstream, Retryable: true, Err: err}
+	}
+
+	// Some clients report a deadline without wrapping context.DeadlineExceeded
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &AIError{Kind: AIErrorTimeout, Retryable: true, Err: err}
+	}
+	return err
+}
+
+// upstreamError is a provider failure; server errors are worth retrying, client errors aren't
+func upstreamError(status int, err error) *AIError {
+	return &AIError{
+		Kind:       AIErrorUpstream,
+		StatusCode: status,
+		Retryable:  status >= http.StatusInternalServerError,
+		Err:        err,
+	}
+}
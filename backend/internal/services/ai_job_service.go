@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"ai-blog-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AI job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+const (
+	// maxJobAttempts is how many times a job is tried before it fails for good
+	maxJobAttempts = 5
+	// jobRetryBaseDelay is the wait before the first retry, doubling for each one after
+	jobRetryBaseDelay = 10 * time.Second
+	jobRetryMaxDelay  = 5 * time.Minute
+	// jobLease is how long a job may run before it is assumed its worker died
+	jobLease = 15 * time.Minute
+)
+
+// ErrUnknownJobOperation is returned for operations that can't run as background jobs
+var ErrUnknownJobOperation = errors.New("unknown AI job operation")
+
+// ErrJobLeaseExpired is recorded on jobs whose worker died on their last attempt
+var ErrJobLeaseExpired = errors.New("AI job did not finish within its lease")
+
+// CreateAIJobRequest queues an AI request. Input is the body the matching
+// synchronous endpoint takes.
+type CreateAIJobRequest struct {
+	Operation string          `json:"operation" binding:"required"` // generate_content, generate_meta or transform_text
+	Input     json.RawMessage `json:"input" binding:"required"`
+}
+
+// aiJobOperation decodes and runs one kind of AI request in the background
+type aiJobOperation struct {
+	newInput func() interface{}
+	run      func(ctx context.Context, ai *AIService, input interface{}) (result interface{}, err error)
+}
+
+// aiJobOperations are the AI requests that can be queued, by usage operation name
+var aiJobOperations = map[string]aiJobOperation{
+	"generate_content": {
+		newInput: func() interface{} { return &GenerateContentRequest{} },
+		run: func(ctx context.Context, ai *AIService, input interface{}) (interface{}, error) {
+			return ai.GenerateContent(ctx, *input.(*GenerateContentRequest))
+		},
+	},
+	"generate_meta": {
+		newInput: func() interface{} { return &GenerateMetaRequest{} },
+		run: func(ctx context.Context, ai *AIService, input interface{}) (interface{}, error) {
+			return ai.GenerateMeta(ctx, *input.(*GenerateMetaRequest))
+		},
+	},
+	"transform_text": {
+		newInput: func() interface{} { return &TransformTextRequest{} },
+		run: func(ctx context.Context, ai *AIService, input interface{}) (interface{}, error) {
+			return ai.TransformText(ctx, *input.(*TransformTextRequest))
+		},
+	},
+}
+
+// NewAIJobInput returns a pointer to the request type taken by a job operation,
+// ready to be decoded and validated
+func NewAIJobInput(operation string) (interface{}, error) {
+	op, ok := aiJobOperations[operation]
+	if !ok {
+		return nil, ErrUnknownJobOperation
+	}
+	return op.newInput(), nil
+}
+
+type AIJobService struct {
+	db           *gorm.DB
+	aiService    *AIService
+	usageService *UsageService
+	userService  *UserService // Supplies the voice profiles of job owners
+}
+
+func NewAIJobService(db *gorm.DB, aiService *AIService, usageService *UsageService, userService *UserService) *AIJobService {
+	return &AIJobService{
+		db:           db,
+		aiService:    aiService,
+		usageService: usageService,
+		userService:  userService,
+	}
+}
+
+// Enqueue queues an AI request for the worker pool
+func (s *AIJobService) Enqueue(clerkUserID, operation string, input interface{}) (*models.AIJob, error) {
+	if _, ok := aiJobOperations[operation]; !ok {
+		return nil, ErrUnknownJobOperation
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.AIJob{
+		ClerkUserID: clerkUserID,
+		Operation:   operation,
+		Status:      JobQueued,
+		Input:       data,
+		RunAt:       time.Now(),
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetJob returns one of the user's jobs
+func (s *AIJobService) GetJob(id, clerkUserID string) (*models.AIJob, error) {
+	var job models.AIJob
+	err := s.db.Where("id = ? AND clerk_user_id = ?", id, clerkUserID).First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimJob marks the next due job as running and returns it, or nil if there is
+// none. SKIP LOCKED lets any number of workers and replicas claim jobs at once
+// without handing the same job to two of them.
+func (s *AIJobService) ClaimJob() (*models.AIJob, error) {
+	var job *models.AIJob
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Jobs whose worker died on their last attempt aren't tried again
+		err := tx.Model(&models.AIJob{}).
+			Where("status = ? AND started_at < ? AND attempts >= ?", JobRunning, now.Add(-jobLease), maxJobAttempts).
+			Updates(map[string]interface{}{
+				"status":      JobFailed,
+				"error":       ErrJobLeaseExpired.Error(),
+				"finished_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		var jobs []models.AIJob
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND started_at < ? AND attempts < ?)",
+				JobQueued, now, JobRunning, now.Add(-jobLease), maxJobAttempts).
+			Order("run_at").Limit(1).Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+
+		job = &jobs[0]
+		job.Status = JobRunning
+		job.Attempts++
+		job.StartedAt = &now
+		return tx.Model(job).Updates(map[string]interface{}{
+			"status":     job.Status,
+			"attempts":   job.Attempts,
+			"started_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// RunJob runs a claimed job and stores its outcome. Failures the AI provider may
+// recover from are retried with exponential backoff, as are jobs interrupted by
+// ctx being cancelled.
+func (s *AIJobService) RunJob(ctx context.Context, job *models.AIJob) error {
+	op, ok := aiJobOperations[job.Operation]
+	if !ok {
+		return s.failJob(job, ErrUnknownJobOperation)
+	}
+
+	input := op.newInput()
+	if err := json.Unmarshal(job.Input, input); err != nil {
+		return s.failJob(job, err)
+	}
+
+	if req, ok := input.(VoiceRequest); ok {
+		voice, err := s.userService.GetVoiceProfile(job.ClerkUserID)
+		if err != nil {
+			log.Printf("Warning: Could not load voice profile for %s: %v", job.ClerkUserID, err)
+		}
+		req.UseDefaultVoice(voice)
+	}
+
+	// Every attempt's tokens are recorded, whether it succeeds or not. Failed calls
+	// are retried as a whole job, so the provider is only called once per attempt.
+	ctx, usage := TrackUsage(withoutRetries(s.usageService.WithQuota(ctx, job.ClerkUserID)))
+
+	// The quota was checked when the job was queued, but the user may have spent
+	// it since, or on an earlier attempt of this job
+	if err := checkQuota(ctx); err != nil {
+		var quotaErr *QuotaExceededError
+		if !errors.As(err, &quotaErr) && job.Attempts < maxJobAttempts {
+			return s.retryJob(job, err)
+		}
+		return s.failJob(job, err)
+	}
+
+	result, err := op.run(ctx, s.aiService, input)
+	if spent := usage.Usage(); spent.TotalTokens > 0 || spent.CacheHits > 0 {
+		if err := s.usageService.Record(job.ClerkUserID, job.Operation, spent); err != nil {
+			log.Printf("Warning: Could not record AI usage for %s: %v", job.ClerkUserID, err)
+		}
+	}
+	if err != nil {
+		if isRetryableAIError(err) && job.Attempts < maxJobAttempts {
+			return s.retryJob(job, err)
+		}
+		return s.failJob(job, err)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return s.failJob(job, err)
+	}
+
+	return s.db.Model(job).Updates(map[string]interface{}{
+		"status":      JobSucceeded,
+		"result":      data,
+		"error":       "",
+		"finished_at": time.Now(),
+	}).Error
+}
+
+func (s *AIJobService) retryJob(job *models.AIJob, cause error) error {
+	delay := jobRetryBaseDelay << (job.Attempts - 1)
+	if delay > jobRetryMaxDelay {
+		delay = jobRetryMaxDelay
+	}
+
+	return s.db.Model(job).Updates(map[string]interface{}{
+		"status": JobQueued,
+		"error":  cause.Error(),
+		"run_at": time.Now().Add(delay),
+	}).Error
+}
+
+func (s *AIJobService) failJob(job *models.AIJob, cause error) error {
+	return s.db.Model(job).Updates(map[string]interface{}{
+		"status":      JobFailed,
+		"error":       cause.Error(),
+		"finished_at": time.Now(),
+	}).Error
+}
+
+// isRetryableAIError reports whether a failed AI call may succeed if tried again later
+func isRetryableAIError(err error) bool {
+	var aiErr *AIError
+	if errors.As(err, &aiErr) {
+		return aiErr.Retryable
+	}
+	// The worker was stopped mid-job
+	return errors.Is(err, context.Canceled)
+}
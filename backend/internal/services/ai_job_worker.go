@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// AIJobWorkerPool runs queued AI jobs in the background
+type AIJobWorkerPool struct {
+	jobService   *AIJobService
+	workers      int
+	pollInterval time.Duration
+}
+
+func NewAIJobWorkerPool(jobService *AIJobService, workers int, pollInterval time.Duration) *AIJobWorkerPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if pollInterval <= 0 {
+		pollInterval = 2 * time.Second
+	}
+	return &AIJobWorkerPool{jobService: jobService, workers: workers, pollInterval: pollInterval}
+}
+
+// Start runs the workers in the background until the context is cancelled
+func (p *AIJobWorkerPool) Start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		go p.work(ctx)
+	}
+}
+
+// work runs jobs back to back while there are any, and polls for new ones otherwise
+func (p *AIJobWorkerPool) work(ctx context.Context) {
+	for {
+		job, err := p.jobService.ClaimJob()
+		if err != nil {
+			log.Printf("Warning: Could not claim AI job: %v", err)
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.pollInterval):
+			}
+			continue
+		}
+
+		if err := p.jobService.RunJob(ctx, job); err != nil {
+			log.Printf("Warning: Could not save result of AI job %s: %v", job.ID, err)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ai-blog-backend/internal/models"
+)
+
+// ErrEmptyOutline is returned when asked to write a post from an outline with no sections
+var ErrEmptyOutline = errors.New("outline needs at least one section with a heading")
+
+// ErrOutlineTooLong is returned when asked to write a post from an outline with
+// more than maxOutlineSections sections
+var ErrOutlineTooLong = fmt.Errorf("outline can have at most %d sections", maxOutlineSections)
+
+const (
+	maxOutlineSections = 12  // Room to add a few sections to the longest generated outline
+	minSectionWords    = 150 // So many short sections don't become a list of stubs
+)
+
+type GenerateOutlineRequest struct {
+	Title       string               `json:"title" binding:"required"`
+	Description string               `json:"description" binding:"required"`
+	Tone        string               `json:"tone"`   // professional, casual, technical, friendly
+	Length      string               `json:"length"` // short, medium, long
+	BlogID      string               `json:"blogId"` // Optional draft to store the outline on
+	Voice       *models.VoiceProfile `json:"voice"`  // Overrides the author's saved voice profile
+	Force       bool                 `json:"force"`  // Skip cached responses
+}
+
+type GenerateOutlineResponse struct {
+	Outline models.BlogOutline `json:"outline"`
+	Usage   TokenUsage         `json:"usage"`
+}
+
+type GenerateFromOutlineRequest struct {
+	Title       string               `json:"title" binding:"required"`
+	Description string               `json:"description"`
+	Tone        string               `json:"tone"`   // professional, casual, technical, friendly
+	Length      string               `json:"length"` // short, medium, long
+	Outline     models.BlogOutline   `json:"outline" binding:"required"`
+	BlogID      string               `json:"blogId"` // Optional draft to store the edited outline on
+	Voice       *models.VoiceProfile `json:"voice"`  // Overrides the author's saved voice profile
+	Force       bool                 `json:"force"`  // Skip cached responses
+}
+
+// outlineShape returns the number of sections and total words to aim for at a given length
+func outlineShape(length string) (minSections, maxSections, words int) {
+	switch length {
+	case "short":
+		return 3, 4, 650
+	case "long":
+		return 6, 8, 2000
+	default:
+		return 4, 6, 1150
+	}
+}
+
+// GenerateOutline drafts an editable outline of headings and key points for a post
+func (s *AIService) GenerateOutline(ctx context.Context, req GenerateOutlineRequest) (*GenerateOutlineResponse, error) {
+	ctx, cancel := s.withTimeout(ctx, "generate_outline")
+	defer cancel()
+	ctx, usage := TrackUsage(bypassCache(ctx, req.Force))
+	minSections, maxSections, _ := outlineShape(req.Length)
+
+	prompt := fmt.Sprintf(`Create an outline for a blog post with the following details:
+
+Title: %s
+Description: %s
+Tone: %s
+
+The outline should have %d-%d sections in reading order, starting with an introduction and ending with a conclusion.
+Give each section a heading and 2-5 key points it should cover.%s
+
+Respond with a JSON object in exactly this shape:
+{"sections": [{"heading": "...", "keyPoints": ["...", "..."]}]}`,
+		req.Title, req.Description, normalizeTone(req.Tone), minSections, maxSections, voiceInstructions(req.Voice))
+
+	var outline models.BlogOutline
+	err := s.generateJSON(ctx, "generate outline", prompt, 800, &outline, func() []string {
+		outline = cleanOutline(outline)
+		if len(outline.Sections) < minSections || len(outline.Sections) > maxSections {
+			return []string{fmt.Sprintf("sections must contain %d-%d sections, but it has %d", minSections, maxSections, len(outline.Sections))}
+		}
+		for i, section := range outline.Sections {
+			if len(section.KeyPoints) == 0 {
+				return []string{fmt.Sprintf("section %d (%q) has no key points", i+1, section.Heading)}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate outline: %w", err)
+	}
+
+	return &GenerateOutlineResponse{Outline: outline, Usage: usage.Usage()}, nil
+}
+
+// GenerateFromOutline writes a post one section at a time from an (edited) outline.
+// Writing per section keeps long posts coherent and each call within token limits.
+func (s *AIService) GenerateFromOutline(ctx context.Context, req GenerateFromOutlineRequest) (*GenerateContentResponse, error) {
+	outline := cleanOutline(req.Outline)
+	if len(outline.Sections) == 0 {
+		return nil, ErrEmptyOutline
+	}
+	if len(outline.Sections) > maxOutlineSections {
+		return nil, ErrOutlineTooLong
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "generate_from_outline")
+	defer cancel()
+	ctx, usage := TrackUsage(bypassCache(ctx, req.Force))
+	tone := normalizeTone(req.Tone)
+	_, _, totalWords := outlineShape(req.Length)
+	sectionWords := totalWords / len(outline.Sections)
+	if sectionWords < minSectionWords {
+		sectionWords = minSectionWords
+	}
+
+	headings := make([]string, len(outline.Sections))
+	for i, section := range outline.Sections {
+		headings[i] = fmt.Sprintf("%d. %s", i+1, section.Heading)
+	}
+
+	sections := make([]string, 0, len(outline.Sections))
+	for i, section := range outline.Sections {
+		// Each section is a separate call, so stop once the author runs out of tokens
+		if err := checkQuota(ctx); err != nil {
+			return nil, err
+		}
+
+		previous := "(this is the first section)"
+		if i > 0 {
+			previous = lastRunes(sections[i-1], 600)
+		}
+
+		prompt := fmt.Sprintf(`You are writing a blog post one section at a time.
+
+Title: %s
+Description: %s
+Tone: %s
+
+Full outline:
+%s
+
+End of the previous section, for continuity:
+"""
+%s
+"""
+
+Write section %d, "%s", covering these key points:
+- %s
+
+Write about %d words in markdown. Start with the heading "## %s" and write only this section.
+Do not repeat earlier sections or preview later ones.%s`,
+			req.Title, req.Description, tone, strings.Join(headings, "\n"), previous,
+			i+1, section.Heading, strings.Join(section.KeyPoints, "\n- "), sectionWords, section.Heading,
+			voiceInstructions(req.Voice))
+
+		maxTokens := sectionWords * 2
+		if maxTokens > 1500 {
+			maxTokens = 1500
+		}
+
+		resp, err := s.complete(ctx, prompt, maxTokens)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate section %d: %w", i+1, err)
+		}
+		sections = append(sections, strings.TrimSpace(resp.Content))
+	}
+
+	content := strings.Join(sections, "\n\n")
+
+	tags, condensed, err := s.generateTags(ctx, req.Title, content)
+	if err != nil {
+		// If tag generation fails, continue with empty tags
+		tags = []string{}
+	}
+
+	return &GenerateContentResponse{
+		Content:        content,
+		Tags:           tags,
+		InputCondensed: condensed,
+		Usage:          usage.Usage(),
+	}, nil
+}
+
+// cleanOutline trims headings and key points and drops empty entries
+func cleanOutline(outline models.BlogOutline) models.BlogOutline {
+	cleaned := models.BlogOutline{Sections: []models.OutlineSection{}}
+	for _, section := range outline.Sections {
+		heading := strings.TrimSpace(strings.TrimLeft(section.Heading, "# "))
+		if heading == "" {
+			continue
+		}
+
+		keyPoints := []string{}
+		for _, point := range section.KeyPoints {
+			if point = strings.TrimSpace(point); point != "" {
+				keyPoints = append(keyPoints, point)
+			}
+		}
+		cleaned.Sections = append(cleaned.Sections, models.OutlineSection{Heading: heading, KeyPoints: keyPoints})
+	}
+	return cleaned
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// Length rules for generated SEO meta information, in characters
+const (
+	metaTitleMinLength       = 50
+	metaTitleMaxLength       = 60
+	metaDescriptionMinLength = 150
+	metaDescriptionMaxLength = 160
+	minTags                  = 5
+	maxTags                  = 7
+)
+
+// maxJSONAttempts is how many times a structured AI response is requested before giving up
+const maxJSONAttempts = 3
+
+// ErrInvalidAIOutput is matched by errors for AI responses that never passed validation
+var ErrInvalidAIOutput = errors.New("AI returned invalid output")
+
+// AIOutputError reports an AI response that was still invalid after every retry
+type AIOutputError struct {
+	Operation string
+	Attempts  int
+	Problems  []string // Validation problems with the last response
+}
+
+func (e *AIOutputError) Error() string {
+	return fmt.Sprintf("%s: invalid AI output after %d attempts: %s",
+		e.Operation, e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+func (e *AIOutputError) Unwrap() error {
+	return ErrInvalidAIOutput
+}
+
+// generateJSON asks the model for a JSON object, decodes it into out and checks it with
+// validate, which may also normalise out in place. Invalid responses are retried with
+// the problems fed back to the model.
+func (s *AIService) generateJSON(ctx context.Context, operation, prompt string, maxTokens int, out interface{}, validate func() []string) error {
+	messages := []ChatMessage{
+		{Role: RoleSystem, Content: "You are a helpful assistant. Always respond with a single valid JSON object and nothing else."},
+		{Role: RoleUser, Content: prompt},
+	}
+
+	var problems []string
+	for attempt := 1; attempt <= maxJSONAttempts; attempt++ {
+		req := TextGenerationRequest{Messages: messages, MaxTokens: maxTokens, JSON: true}
+		resp, err := s.generate(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		problems = decodeJSONOutput(resp.Content, out)
+		if len(problems) == 0 {
+			problems = validate()
+		}
+		if len(problems) == 0 {
+			return nil
+		}
+		// Don't serve an invalid response again on the next request
+		if fitted, err := fitRequest(s.generator.Model(), req); err == nil {
+			forgetResponse(ctx, s.generator, fitted)
+		}
+
+		messages = append(messages,
+			ChatMessage{Role: RoleAssistant, Content: resp.Content},
+			ChatMessage{Role: RoleUser, Content: "That response was invalid:\n- " + strings.Join(problems, "\n- ") +
+				"\nRespond again with the corrected JSON object only."},
+		)
+	}
+
+	return &AIOutputError{Operation: operation, Attempts: maxJSONAttempts, Problems: problems}
+}
+
+// decodeJSONOutput resets out and decodes a model response into it
+func decodeJSONOutput(content string, out interface{}) []string {
+	target := reflect.ValueOf(out).Elem()
+	target.Set(reflect.Zero(target.Type()))
+
+	// Some OpenAI-compatible servers wrap JSON in a markdown code fence
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	if err := json.Unmarshal([]byte(content), out); err != nil {
+		return []string{fmt.Sprintf("response is not valid JSON of the requested shape: %v", err)}
+	}
+	return nil
+}
+
+// checkLength reports a problem unless value is between min and max characters long
+func checkLength(field, value string, min, max int) []string {
+	length := utf8.RuneCountInString(value)
+	if length < min || length > max {
+		return []string{fmt.Sprintf("%s must be %d-%d characters long, but it is %d characters", field, min, max, length)}
+	}
+	return nil
+}
+
+func checkTagCount(tags []string) []string {
+	if len(tags) < minTags || len(tags) > maxTags {
+		return []string{fmt.Sprintf("tags must contain %d-%d tags, but it has %d", minTags, maxTags, len(tags))}
+	}
+	return nil
+}
+
+// cleanTags trims tags and drops empty and duplicate ones
+func cleanTags(tags []string) []string {
+	seen := make(map[string]bool)
+	cleaned := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, tag)
+	}
+	return cleaned
+}
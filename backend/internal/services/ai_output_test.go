@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testMeta struct {
+	MetaTitle string   `json:"metaTitle"`
+	Tags      []string `json:"tags"`
+}
+
+func TestDecodeJSONOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *testMeta // nil if the content should be rejected
+	}{
+		{name: "object", content: `{"metaTitle": "Title", "tags": ["a", "b"]}`, want: &testMeta{MetaTitle: "Title", Tags: []string{"a", "b"}}},
+		{name: "json code fence", content: "```json\n{\"metaTitle\": \"Title\"}\n```", want: &testMeta{MetaTitle: "Title"}},
+		{name: "bare code fence", content: "\n  ```\n{\"tags\": []}\n```  \n", want: &testMeta{Tags: []string{}}},
+		{name: "unknown fields", content: `{"metaTitle": "Title", "extra": 1}`, want: &testMeta{MetaTitle: "Title"}},
+		{name: "prose around the object", content: `Here you go: {"metaTitle": "Title"}`},
+		{name: "wrong field type", content: `{"metaTitle": ["Title"]}`},
+		{name: "truncated", content: `{"metaTitle": "Tit`},
+		{name: "empty", content: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Values from an earlier attempt must not survive into this one
+			out := testMeta{MetaTitle: "stale", Tags: []string{"stale"}}
+			problems := decodeJSONOutput(tt.content, &out)
+
+			if tt.want == nil {
+				if len(problems) == 0 {
+					t.Errorf("decodeJSONOutput(%q) accepted %+v", tt.content, out)
+				}
+				return
+			}
+			if len(problems) > 0 {
+				t.Fatalf("decodeJSONOutput(%q) problems = %v", tt.content, problems)
+			}
+			if !reflect.DeepEqual(out, *tt.want) {
+				t.Errorf("decodeJSONOutput(%q) = %+v, want %+v", tt.content, out, *tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []string
+		wantTitle string
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "valid", responses: []string{`{"metaTitle": "Title"}`}, wantTitle: "Title", wantCalls: 1},
+		{name: "not JSON, then valid", responses: []string{`not json`, `{"metaTitle": "Title"}`}, wantTitle: "Title", wantCalls: 2},
+		{name: "fails validation twice", responses: []string{`{}`, `{"metaTitle": ""}`, `{"metaTitle": "Title"}`}, wantTitle: "Title", wantCalls: 3},
+		{name: "never valid", responses: []string{`{}`, `{}`, `{}`, `{"metaTitle": "Too late"}`}, wantCalls: maxJSONAttempts, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := NewFakeGenerator(tt.responses...)
+			s := NewAIService(generator, nil, AITimeouts{})
+
+			var out testMeta
+			err := s.generateJSON(context.Background(), "test", "prompt", 100, &out, func() []string {
+				if out.MetaTitle == "" {
+					return []string{"metaTitle is empty"}
+				}
+				return nil
+			})
+
+			if len(generator.Requests) != tt.wantCalls {
+				t.Errorf("made %d requests, want %d", len(generator.Requests), tt.wantCalls)
+			}
+			if tt.wantErr {
+				var outputErr *AIOutputError
+				if !errors.As(err, &outputErr) || !errors.Is(err, ErrInvalidAIOutput) {
+					t.Fatalf("generateJSON() error = %v, want an *AIOutputError", err)
+				}
+				if outputErr.Attempts != maxJSONAttempts || len(outputErr.Problems) == 0 {
+					t.Errorf("generateJSON() error = %+v", outputErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("generateJSON() error = %v", err)
+			}
+			if out.MetaTitle != tt.wantTitle {
+				t.Errorf("MetaTitle = %q, want %q", out.MetaTitle, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestGenerateJSONFeedsBackProblems(t *testing.T) {
+	generator := NewFakeGenerator(`{"metaTitle": ""}`, `{"metaTitle": "Title"}`)
+	s := NewAIService(generator, nil, AITimeouts{})
+
+	var out testMeta
+	err := s.generateJSON(context.Background(), "test", "prompt", 100, &out, func() []string {
+		if out.MetaTitle == "" {
+			return []string{"metaTitle is empty"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	retry := generator.Requests[1].Messages
+	if len(retry) != 4 {
+		t.Fatalf("retry has %d messages, want the prompt, the invalid response and the problems", len(retry))
+	}
+	if retry[2].Role != RoleAssistant || retry[2].Content != `{"metaTitle": ""}` {
+		t.Errorf("retry message 3 = %+v, want the invalid response", retry[2])
+	}
+	if retry[3].Role != RoleUser || !strings.Contains(retry[3].Content, "metaTitle is empty") {
+		t.Errorf("retry message 4 = %+v, want the validation problems", retry[3])
+	}
+}
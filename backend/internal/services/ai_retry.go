@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how RetryingGenerator retries failed model calls
+type RetryPolicy struct {
+	MaxAttempts int           // Including the first call
+	BaseDelay   time.Duration // Upper bound of the first wait, doubling for each one after
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used for fields of a RetryPolicy that are left zero
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 8 * time.Second}
+
+// backoff returns a random wait of up to BaseDelay * 2^(attempt-1), capped at MaxDelay
+// ("full jitter"), so clients that failed together don't retry together
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	limit := p.MaxDelay
+	if shift := attempt - 1; shift < 30 && p.BaseDelay<<shift < limit {
+		limit = p.BaseDelay << shift
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(limit)))
+}
+
+type noRetryKey struct{}
+
+// withoutRetries returns a context whose model calls RetryingGenerator makes only
+// once, for callers that retry failed calls on their own schedule
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// RetryingGenerator is a TextGenerator that retries rate limits, server errors
+// and dropped connections with jittered exponential backoff
+type RetryingGenerator struct {
+	next   TextGenerator
+	policy RetryPolicy
+}
+
+func NewRetryingGenerator(next TextGenerator, policy RetryPolicy) *RetryingGenerator {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	return &RetryingGenerator{next: next, policy: policy}
+}
+
+func (g *RetryingGenerator) Model() string {
+	return g.next.Model()
+}
+
+func (g *RetryingGenerator) Generate(ctx context.Context, req TextGenerationRequest) (*TextGenerationResult, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := g.next.Generate(ctx, req)
+		if err == nil || !g.wait(ctx, attempt, err) {
+			return result, err
+		}
+	}
+}
+
+// Stream retries only until the first chunk has been passed on, since a partial
+// response can't be taken back
+func (g *RetryingGenerator) Stream(ctx context.Context, req TextGenerationRequest, onDelta func(delta string) error) (*TextGenerationResult, error) {
+	started := false
+	forward := func(delta string) error {
+		started = true
+		return onDelta(delta)
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := g.next.Stream(ctx, req, forward)
+		if err == nil || started || !g.wait(ctx, attempt, err) {
+			return result, err
+		}
+	}
+}
+
+// wait sleeps before the next attempt and reports whether there should be one
+func (g *RetryingGenerator) wait(ctx context.Context, attempt int, err error) bool {
+	var aiErr *AIError
+	if attempt >= g.policy.MaxAttempts || !errors.As(err, &aiErr) || !aiErr.Retryable {
+		return false
+	}
+	if noRetry, _ := ctx.Value(noRetryKey{}).(bool); noRetry {
+		return false
+	}
+
+	delay := g.policy.backoff(attempt)
+	// Give up now rather than fail with a timeout after waiting
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return false
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestClassifyAIError(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	plain := errors.New("something else")
+	existing := &AIError{Kind: AIErrorUpstream, Err: plain}
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		err           error
+		want          error // Err* value the result matches, nil if err is returned unchanged
+		wantRetryable bool
+		wantStatus    int
+	}{
+		{
+			name:          "rate limited",
+			err:           &openai.APIError{HTTPStatusCode: 429, Code: "rate_limit_exceeded"},
+			want:          ErrAIQuota,
+			wantRetryable: true,
+			wantStatus:    429,
+		},
+		{
+			name:       "out of credit",
+			err:        &openai.APIError{HTTPStatusCode: 429, Code: "insufficient_quota"},
+			want:       ErrAIQuota,
+			wantStatus: 429,
+		},
+		{
+			name:       "content filter",
+			err:        &openai.APIError{HTTPStatusCode: 400, Code: "content_filter"},
+			want:       ErrAIContentFiltered,
+			wantStatus: 400,
+		},
+		{
+			name:       "content policy violation",
+			err:        &openai.APIError{HTTPStatusCode: 400, Code: "content_policy_violation"},
+			want:       ErrAIContentFiltered,
+			wantStatus: 400,
+		},
+		{
+			name:          "server error",
+			err:           &openai.APIError{HTTPStatusCode: 503},
+			want:          ErrAIUpstream,
+			wantRetryable: true,
+			wantStatus:    503,
+		},
+		{
+			name:       "bad request",
+			err:        &openai.APIError{HTTPStatusCode: 400, Code: "invalid_request_error"},
+			want:       ErrAIUpstream,
+			wantStatus: 400,
+		},
+		{
+			name:          "wrapped API error",
+			err:           fmt.Errorf("generate: %w", &openai.APIError{HTTPStatusCode: 500}),
+			want:          ErrAIUpstream,
+			wantRetryable: true,
+			wantStatus:    500,
+		},
+		{
+			name:          "rate limited without an error body",
+			err:           &openai.RequestError{HTTPStatusCode: 429, Err: plain},
+			want:          ErrAIQuota,
+			wantRetryable: true,
+			wantStatus:    429,
+		},
+		{
+			name:          "bad gateway without an error body",
+			err:           &openai.RequestError{HTTPStatusCode: 502, Err: plain},
+			want:          ErrAIUpstream,
+			wantRetryable: true,
+			wantStatus:    502,
+		},
+		{
+			name:       "not found without an error body",
+			err:        &openai.RequestError{HTTPStatusCode: 404, Err: plain},
+			want:       ErrAIUpstream,
+			wantStatus: 404,
+		},
+		{
+			name:          "deadline exceeded",
+			err:           fmt.Errorf("stream: %w", context.DeadlineExceeded),
+			want:          ErrAITimeout,
+			wantRetryable: true,
+		},
+		{
+			name:          "network timeout",
+			err:           &net.DNSError{Err: "i/o timeout", IsTimeout: true},
+			want:          ErrAITimeout,
+			wantRetryable: true,
+		},
+		{
+			name:          "dropped connection",
+			err:           &net.OpError{Op: "read", Err: errors.New("connection reset by peer")},
+			want:          ErrAIUpstream,
+			wantRetryable: true,
+		},
+		{
+			name:          "unwrapped error after the deadline",
+			ctx:           expired,
+			err:           plain,
+			want:          ErrAITimeout,
+			wantRetryable: true,
+		},
+		{
+			name: "cancelled by the caller",
+			err:  context.Canceled,
+		},
+		{
+			name: "unknown error",
+			err:  plain,
+		},
+		{
+			name: "already classified",
+			err:  existing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			got := classifyAIError(ctx, tt.err)
+			if tt.want == nil {
+				if got != tt.err {
+					t.Errorf("classifyAIError(%v) = %#v, want the error unchanged", tt.err, got)
+				}
+				return
+			}
+
+			var aiErr *AIError
+			if !errors.As(got, &aiErr) {
+				t.Fatalf("classifyAIError(%v) = %#v, want an *AIError", tt.err, got)
+			}
+			if !errors.Is(got, tt.want) {
+				t.Errorf("classifyAIError(%v) = %v, want it to match %v", tt.err, got, tt.want)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("classifyAIError(%v) doesn't unwrap to the original error", tt.err)
+			}
+			if aiErr.Retryable != tt.wantRetryable {
+				t.Errorf("Retryable = %v, want %v", aiErr.Retryable, tt.wantRetryable)
+			}
+			if aiErr.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", aiErr.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		limit   time.Duration // Every wait must be shorter than this
+	}{
+		{name: "first retry", policy: policy, attempt: 1, limit: 100 * time.Millisecond},
+		{name: "doubles", policy: policy, attempt: 2, limit: 200 * time.Millisecond},
+		{name: "doubles again", policy: policy, attempt: 4, limit: 800 * time.Millisecond},
+		{name: "capped", policy: policy, attempt: 5, limit: time.Second},
+		{name: "capped without overflowing", policy: policy, attempt: 64, limit: time.Second},
+		{name: "no delay", policy: RetryPolicy{}, attempt: 1, limit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var longest time.Duration
+			for i := 0; i < 200; i++ {
+				delay := tt.policy.backoff(tt.attempt)
+				if delay < 0 || (delay >= tt.limit && delay != 0) {
+					t.Fatalf("backoff(%d) = %v, want 0 <= delay < %v", tt.attempt, delay, tt.limit)
+				}
+				if delay > longest {
+					longest = delay
+				}
+			}
+			// Full jitter spreads waits over the whole range
+			if longest < tt.limit/2 {
+				t.Errorf("longest of 200 waits = %v, want some above %v", longest, tt.limit/2)
+			}
+		})
+	}
+}
+
+// flakyGenerator fails with each of errs in turn, then succeeds
+type flakyGenerator struct {
+	errs    []error
+	partial bool // Stream sends a chunk before failing
+	calls   int
+}
+
+func (g *flakyGenerator) Model() string {
+	return "flaky"
+}
+
+func (g *flakyGenerator) Generate(ctx context.Context, req TextGenerationRequest) (*TextGenerationResult, error) {
+	g.calls++
+	if g.calls <= len(g.errs) {
+		return nil, g.errs[g.calls-1]
+	}
+	return &TextGenerationResult{Content: "ok"}, nil
+}
+
+func (g *flakyGenerator) Stream(ctx context.Context, req TextGenerationRequest, onDelta func(delta string) error) (*TextGenerationResult, error) {
+	if g.partial {
+		if err := onDelta("partial "); err != nil {
+			return nil, err
+		}
+	}
+	result, err := g.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return result, onDelta(result.Content)
+}
+
+func TestRetryingGenerator(t *testing.T) {
+	rateLimited := &AIError{Kind: AIErrorQuota, Retryable: true, Err: errors.New("429")}
+	outOfCredit := &AIError{Kind: AIErrorQuota, Err: errors.New("insufficient_quota")}
+	unclassified := errors.New("boom")
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "succeeds first time", wantCalls: 1},
+		{name: "retries until it succeeds", errs: []error{rateLimited, rateLimited}, wantCalls: 3},
+		{name: "gives up after MaxAttempts", errs: []error{rateLimited, rateLimited, rateLimited, rateLimited}, wantCalls: 3, wantErr: rateLimited},
+		{name: "doesn't retry permanent errors", errs: []error{outOfCredit}, wantCalls: 1, wantErr: outOfCredit},
+		{name: "doesn't retry unclassified errors", errs: []error{unclassified}, wantCalls: 1, wantErr: unclassified},
+		{name: "caller retries on its own", ctx: withoutRetries(context.Background()), errs: []error{rateLimited}, wantCalls: 1, wantErr: rateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			next := &flakyGenerator{errs: tt.errs}
+			g := NewRetryingGenerator(next, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+			result, err := g.Generate(ctx, TextGenerationRequest{})
+			if err != tt.wantErr {
+				t.Errorf("Generate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && result.Content != "ok" {
+				t.Errorf("Generate() = %q, want %q", result.Content, "ok")
+			}
+			if next.calls != tt.wantCalls {
+				t.Errorf("made %d calls, want %d", next.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryingGeneratorStream(t *testing.T) {
+	rateLimited := &AIError{Kind: AIErrorQuota, Retryable: true, Err: errors.New("429")}
+
+	tests := []struct {
+		name      string
+		partial   bool
+		wantCalls int
+		wantText  string
+		wantErr   error
+	}{
+		{name: "retries before anything was sent", wantCalls: 2, wantText: "ok"},
+		{name: "doesn't retry after a chunk was sent", partial: true, wantCalls: 1, wantText: "partial ", wantErr: rateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &flakyGenerator{errs: []error{rateLimited}, partial: tt.partial}
+			g := NewRetryingGenerator(next, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+			var text string
+			_, err := g.Stream(context.Background(), TextGenerationRequest{}, func(delta string) error {
+				text += delta
+				return nil
+			})
+			if err != tt.wantErr {
+				t.Errorf("Stream() error = %v, want %v", err, tt.wantErr)
+			}
+			if text != tt.wantText {
+				t.Errorf("streamed %q, want %q", text, tt.wantText)
+			}
+			if next.calls != tt.wantCalls {
+				t.Errorf("made %d calls, want %d", next.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryingGeneratorDeadline(t *testing.T) {
+	rateLimited := &AIError{Kind: AIErrorQuota, Retryable: true, Err: errors.New("429")}
+	next := &flakyGenerator{errs: []error{rateLimited, rateLimited}}
+	g := NewRetryingGenerator(next, RetryPolicy{MaxAttempts: 3, BaseDelay: 1000 * time.Hour, MaxDelay: 1000 * time.Hour})
+
+	// Practically every wait would outlast the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := g.Generate(ctx, TextGenerationRequest{}); err != rateLimited {
+		t.Errorf("Generate() error = %v, want %v", err, rateLimited)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("Generate() took %v, want it to give up without waiting", elapsed)
+	}
+}
@@ -4,48 +4,157 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/sashabaranov/go-openai"
+	"ai-blog-backend/internal/models"
 )
 
+// Writing tones shared by every AI writing tool
+const (
+	ToneProfessional = "professional"
+	ToneCasual       = "casual"
+	ToneTechnical    = "technical"
+	ToneFriendly     = "friendly"
+)
+
+// AITimeouts limits how long each AI operation may run, retries included
+type AITimeouts struct {
+	Default    time.Duration            // For operations without their own timeout; 0 means no limit
+	Operations map[string]time.Duration // By usage operation name, e.g. generate_content
+}
+
+// DefaultAITimeouts allow the operations that write whole posts more time
+var DefaultAITimeouts = AITimeouts{
+	Default: time.Minute,
+	Operations: map[string]time.Duration{
+		"generate_content":      3 * time.Minute,
+		"generate_from_outline": 5 * time.Minute,
+		"translate_blog":        5 * time.Minute,
+	},
+}
+
+// For returns the timeout of an operation
+func (t AITimeouts) For(operation string) time.Duration {
+	if timeout, ok := t.Operations[operation]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
 type AIService struct {
-	client *openai.Client
+	generator TextGenerator
+	prompts   *PromptService // nil uses the built-in prompts
+	timeouts  AITimeouts
 }
 
-func NewAIService(apiKey string) *AIService {
-	client := openai.NewClient(apiKey)
-	return &AIService{client: client}
+func NewAIService(generator TextGenerator, prompts *PromptService, timeouts AITimeouts) *AIService {
+	return &AIService{generator: generator, prompts: prompts, timeouts: timeouts}
 }
 
 type GenerateContentRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description" binding:"required"`
-	Tone        string `json:"tone"` // professional, casual, technical, friendly
-	Length      string `json:"length"` // short, medium, long
+	Title       string               `json:"title" binding:"required"`
+	Description string               `json:"description" binding:"required"`
+	Tone        string               `json:"tone"`   // professional, casual, technical, friendly
+	Length      string               `json:"length"` // short, medium, long
+	Voice       *models.VoiceProfile `json:"voice"`  // Overrides the author's saved voice profile
+	Force       bool                 `json:"force"`  // Skip cached responses
 }
 
 type GenerateContentResponse struct {
-	Content string   `json:"content"`
-	Tags    []string `json:"tags"`
+	Content        string     `json:"content"`
+	Tags           []string   `json:"tags"`
+	InputCondensed bool       `json:"inputCondensed"` // Content was summarised to fit the model when generating tags
+	Usage          TokenUsage `json:"usage"`
 }
 
 type GenerateMetaRequest struct {
 	Title   string `json:"title" binding:"required"`
 	Content string `json:"content" binding:"required"`
+	Force   bool   `json:"force"` // Skip cached responses
 }
 
 type GenerateMetaResponse struct {
-	MetaTitle       string   `json:"metaTitle"`
-	MetaDescription string   `json:"metaDescription"`
-	Tags            []string `json:"tags"`
+	MetaTitle       string     `json:"metaTitle"`
+	MetaDescription string     `json:"metaDescription"`
+	Tags            []string   `json:"tags"`
+	InputCondensed  bool       `json:"inputCondensed"` // Content was too long for the model and was summarised first
+	Usage           TokenUsage `json:"usage"`
 }
 
-func (s *AIService) GenerateContent(req GenerateContentRequest) (*GenerateContentResponse, error) {
-	tone := req.Tone
-	if tone == "" {
-		tone = "professional"
+func (s *AIService) GenerateContent(ctx context.Context, req GenerateContentRequest) (*GenerateContentResponse, error) {
+	ctx, cancel := s.withTimeout(ctx, "generate_content")
+	defer cancel()
+	ctx, usage := TrackUsage(bypassCache(ctx, req.Force))
+
+	prompt, err := s.contentPrompt(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content: %w", err)
 	}
-	
+
+	resp, err := s.complete(ctx, prompt, 3000)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	content := resp.Content
+
+	// Generate tags
+	tags, condensed, err := s.generateTags(ctx, req.Title, content)
+	if err != nil {
+		// If tag generation fails, continue with empty tags
+		tags = []string{}
+	}
+
+	return &GenerateContentResponse{
+		Content:        content,
+		Tags:           tags,
+		InputCondensed: condensed,
+		Usage:          usage.Usage(),
+	}, nil
+}
+
+// GenerateContentStream generates a blog post like GenerateContent, passing content
+// chunks to onDelta as they arrive. Cancelling ctx aborts the upstream request.
+func (s *AIService) GenerateContentStream(ctx context.Context, req GenerateContentRequest, onDelta func(delta string) error) (*GenerateContentResponse, error) {
+	ctx, cancel := s.withTimeout(ctx, "generate_content")
+	defer cancel()
+	ctx, usage := TrackUsage(bypassCache(ctx, req.Force))
+
+	prompt, err := s.contentPrompt(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	resp, err := s.stream(ctx, TextGenerationRequest{
+		Messages:  []ChatMessage{{Role: RoleUser, Content: prompt}},
+		MaxTokens: 3000,
+	}, onDelta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	tags, condensed, err := s.generateTags(ctx, req.Title, resp.Content)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// If tag generation fails, continue with empty tags
+		tags = []string{}
+	}
+
+	return &GenerateContentResponse{
+		Content:        resp.Content,
+		Tags:           tags,
+		InputCondensed: condensed,
+		Usage:          usage.Usage(),
+	}, nil
+}
+
+// contentPrompt builds the prompt used to write a full blog post
+func (s *AIService) contentPrompt(ctx context.Context, req GenerateContentRequest) (string, error) {
+	tone := normalizeTone(req.Tone)
+
 	length := req.Length
 	if length == "" {
 		length = "medium"
@@ -63,161 +172,189 @@ func (s *AIService) GenerateContent(req GenerateContentRequest) (*GenerateConten
 		wordCount = "800-1500 words"
 	}
 
-	prompt := fmt.Sprintf(`Write a comprehensive blog post with the following details:
-
-Title: %s
-Description: %s
-Tone: %s
-Length: %s
+	prompt, err := s.prompt(ctx, PromptGenerateContent)
+	if err != nil {
+		return "", err
+	}
+	text, err := prompt.Render(map[string]interface{}{
+		"Title":       req.Title,
+		"Description": req.Description,
+		"Tone":        tone,
+		"WordCount":   wordCount,
+	})
+	if err != nil {
+		return "", err
+	}
+	return text + voiceInstructions(req.Voice), nil
+}
 
-Please write a well-structured blog post that:
-1. Has an engaging introduction
-2. Is organized with clear headings and subheadings
-3. Includes practical examples or insights
-4. Has a strong conclusion
-5. Uses a %s tone throughout
-6. Is approximately %s in length
+func (s *AIService) GenerateMeta(ctx context.Context, req GenerateMetaRequest) (*GenerateMetaResponse, error) {
+	ctx, cancel := s.withTimeout(ctx, "generate_meta")
+	defer cancel()
+	ctx, usage := TrackUsage(bypassCache(ctx, req.Force))
 
-Format the response as a complete blog post in markdown format.`, 
-		req.Title, req.Description, tone, wordCount, tone, wordCount)
+	prompt, err := s.prompt(ctx, PromptGenerateMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate meta: %w", err)
+	}
+	metaPrompt := func(content string) (string, error) {
+		return prompt.Render(map[string]interface{}{
+			"Title":              req.Title,
+			"Content":            content,
+			"MetaTitleMin":       metaTitleMinLength,
+			"MetaTitleMax":       metaTitleMaxLength,
+			"MetaDescriptionMin": metaDescriptionMinLength,
+			"MetaDescriptionMax": metaDescriptionMaxLength,
+			"MinTags":            minTags,
+			"MaxTags":            maxTags,
+		})
+	}
 
-	resp, err := s.client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			MaxTokens: 3000,
-		},
-	)
+	emptyPrompt, err := metaPrompt("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate meta: %w", err)
+	}
 
+	content, condensed, err := s.fitContent(ctx, req.Content, countTokens(s.generator.Model(), emptyPrompt)+jsonCallTokens(300))
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %w", err)
+		return nil, fmt.Errorf("failed to generate meta: %w", err)
 	}
 
-	content := resp.Choices[0].Message.Content
+	text, err := metaPrompt(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate meta: %w", err)
+	}
 
-	// Generate tags
-	tags, err := s.generateTags(req.Title, content)
+	var meta GenerateMetaResponse
+	err = s.generateJSON(ctx, "generate meta", text, 300, &meta, func() []string {
+		meta.MetaTitle = strings.TrimSpace(meta.MetaTitle)
+		meta.MetaDescription = strings.TrimSpace(meta.MetaDescription)
+		meta.Tags = cleanTags(meta.Tags)
+
+		var problems []string
+		problems = append(problems, checkLength("metaTitle", meta.MetaTitle, metaTitleMinLength, metaTitleMaxLength)...)
+		problems = append(problems, checkLength("metaDescription", meta.MetaDescription, metaDescriptionMinLength, metaDescriptionMaxLength)...)
+		problems = append(problems, checkTagCount(meta.Tags)...)
+		return problems
+	})
 	if err != nil {
-		// If tag generation fails, continue with empty tags
-		tags = []string{}
+		return nil, fmt.Errorf("failed to generate meta: %w", err)
 	}
 
-	return &GenerateContentResponse{
-		Content: content,
-		Tags:    tags,
-	}, nil
+	meta.InputCondensed = condensed
+	meta.Usage = usage.Usage()
+	return &meta, nil
 }
 
-func (s *AIService) GenerateMeta(req GenerateMetaRequest) (*GenerateMetaResponse, error) {
-	prompt := fmt.Sprintf(`Based on the following blog post title and content, generate SEO-optimized meta information:
-
-Title: %s
-Content: %s
-
-Please provide:
-1. A compelling meta title (50-60 characters)
-2. A descriptive meta description (150-160 characters)
-3. 5-7 relevant tags/keywords
+// generateTags suggests tags for a post. The second return value reports whether
+// the content had to be condensed to fit the model.
+func (s *AIService) generateTags(ctx context.Context, title, content string) ([]string, bool, error) {
+	prompt, err := s.prompt(ctx, PromptGenerateTags)
+	if err != nil {
+		return nil, false, err
+	}
+	tagsPrompt := func(content string) (string, error) {
+		return prompt.Render(map[string]interface{}{
+			"Title":   title,
+			"Content": content,
+			"MinTags": minTags,
+			"MaxTags": maxTags,
+		})
+	}
 
-Format your response as:
-Meta Title: [title]
-Meta Description: [description]
-Tags: [tag1, tag2, tag3, ...]`, req.Title, req.Content)
+	emptyPrompt, err := tagsPrompt("")
+	if err != nil {
+		return nil, false, err
+	}
 
-	resp, err := s.client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			MaxTokens: 300,
-		},
-	)
+	content, condensed, err := s.fitContent(ctx, content, countTokens(s.generator.Model(), emptyPrompt)+jsonCallTokens(100))
+	if err != nil {
+		return nil, false, err
+	}
 
+	text, err := tagsPrompt(content)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate meta: %w", err)
+		return nil, condensed, err
 	}
 
-	content := resp.Choices[0].Message.Content
-	
-	// Parse the response
-	metaTitle, metaDescription, tags := s.parseMetaResponse(content)
+	var output struct {
+		Tags []string `json:"tags"`
+	}
+	err = s.generateJSON(ctx, "generate tags", text, 100, &output, func() []string {
+		output.Tags = cleanTags(output.Tags)
+		return checkTagCount(output.Tags)
+	})
+	if err != nil {
+		return nil, condensed, err
+	}
 
-	return &GenerateMetaResponse{
-		MetaTitle:       metaTitle,
-		MetaDescription: metaDescription,
-		Tags:            tags,
-	}, nil
+	return output.Tags, condensed, nil
 }
 
-func (s *AIService) generateTags(title, content string) ([]string, error) {
-	prompt := fmt.Sprintf(`Based on the following blog post title and content, suggest 5-7 relevant tags/keywords:
-
-Title: %s
-Content: %s
+// prompt chooses the version of a prompt template to use and records it in ctx's usage
+func (s *AIService) prompt(ctx context.Context, name string) (*Prompt, error) {
+	prompt, err := s.prompts.Choose(name)
+	if err != nil {
+		return nil, err
+	}
+	recordPrompt(ctx, prompt.Version)
+	return prompt, nil
+}
 
-Respond with only the tags separated by commas.`, title, content)
+// complete sends a single user prompt to the configured text generator
+func (s *AIService) complete(ctx context.Context, prompt string, maxTokens int) (*TextGenerationResult, error) {
+	return s.generate(ctx, TextGenerationRequest{
+		Messages:  []ChatMessage{{Role: RoleUser, Content: prompt}},
+		MaxTokens: maxTokens,
+	})
+}
 
-	resp, err := s.client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			MaxTokens: 100,
-		},
-	)
+// generate calls the text generator and records the tokens it used.
+// Every model call made by AIService goes through here or through stream.
+func (s *AIService) generate(ctx context.Context, req TextGenerationRequest) (*TextGenerationResult, error) {
+	req, err := fitRequest(s.generator.Model(), req)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.generator.Generate(ctx, req)
+	recordUsage(ctx, req, result)
+	return result, classifyAIError(ctx, err)
+}
 
+// stream calls the text generator in streaming mode and records the tokens it used
+func (s *AIService) stream(ctx context.Context, req TextGenerationRequest, onDelta func(delta string) error) (*TextGenerationResult, error) {
+	req, err := fitRequest(s.generator.Model(), req)
 	if err != nil {
 		return nil, err
 	}
 
-	tagsStr := resp.Choices[0].Message.Content
-	tags := strings.Split(tagsStr, ",")
-	
-	// Clean up tags
-	for i, tag := range tags {
-		tags[i] = strings.TrimSpace(tag)
-	}
-
-	return tags, nil
-}
-
-func (s *AIService) parseMetaResponse(response string) (string, string, []string) {
-	lines := strings.Split(response, "\n")
-	
-	var metaTitle, metaDescription string
-	var tags []string
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "Meta Title:") {
-			metaTitle = strings.TrimSpace(strings.TrimPrefix(line, "Meta Title:"))
-		} else if strings.HasPrefix(line, "Meta Description:") {
-			metaDescription = strings.TrimSpace(strings.TrimPrefix(line, "Meta Description:"))
-		} else if strings.HasPrefix(line, "Tags:") {
-			tagsStr := strings.TrimSpace(strings.TrimPrefix(line, "Tags:"))
-			tagsStr = strings.Trim(tagsStr, "[]")
-			tagsList := strings.Split(tagsStr, ",")
-			for _, tag := range tagsList {
-				tags = append(tags, strings.TrimSpace(tag))
-			}
-		}
+	var streamed strings.Builder
+	result, err := s.generator.Stream(ctx, req, func(delta string) error {
+		streamed.WriteString(delta)
+		return onDelta(delta)
+	})
+	if result == nil && streamed.Len() > 0 {
+		// The stream broke off or the client went away; the chunks sent so far were still paid for
+		recordUsage(ctx, req, &TextGenerationResult{Content: streamed.String(), Model: s.generator.Model()})
+	} else {
+		recordUsage(ctx, req, result)
 	}
+	return result, classifyAIError(ctx, err)
+}
 
-	return metaTitle, metaDescription, tags
-} 
\ No newline at end of file
+// withTimeout limits ctx to the operation's timeout
+func (s *AIService) withTimeout(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+	timeout := s.timeouts.For(operation)
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// normalizeTone falls back to a professional tone when none is given
+func normalizeTone(tone string) string {
+	if tone == "" {
+		return ToneProfessional
+	}
+	return tone
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SummarizeBlog writes a TL;DR and key takeaways for a post
+func (s *AIService) SummarizeBlog(ctx context.Context, title, content string) (*BlogSummary, error) {
+	summaryPrompt := func(content string) string {
+		return fmt.Sprintf(`Summarize the following blog post for readers who are deciding whether to read it.
+
+Write:
+1. A TL;DR summary of 1-3 sentences, at most %d characters
+2. %d-%d key takeaways: short, self-contained bullet points stating what the reader will learn
+
+Use only what the post says. Write in the same language as the post.
+
+Title: %s
+Content:
+"""
+%s
+"""
+
+Respond with a JSON object in exactly this shape:
+{"summary": "...", "keyTakeaways": ["...", "...", "..."]}`,
+			maxSummaryLength, minTakeaways, maxTakeaways, title, content)
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "summarize_blog")
+	defer cancel()
+	ctx, usage := TrackUsage(ctx)
+
+	content, _, err := s.fitContent(ctx, content, countTokens(s.generator.Model(), summaryPrompt(""))+jsonCallTokens(400))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize blog: %w", err)
+	}
+
+	var output struct {
+		Summary      string   `json:"summary"`
+		KeyTakeaways []string `json:"keyTakeaways"`
+	}
+	err = s.generateJSON(ctx, "summarize blog", summaryPrompt(content), 400, &output, func() []string {
+		output.Summary = strings.TrimSpace(output.Summary)
+		takeaways := []string{}
+		for _, takeaway := range output.KeyTakeaways {
+			if takeaway = strings.TrimSpace(strings.TrimLeft(takeaway, "-*• ")); takeaway != "" {
+				takeaways = append(takeaways, takeaway)
+			}
+		}
+		output.KeyTakeaways = takeaways
+
+		var problems []string
+		problems = append(problems, checkLength("summary", output.Summary, 1, maxSummaryLength)...)
+		if len(output.KeyTakeaways) < minTakeaways || len(output.KeyTakeaways) > maxTakeaways {
+			problems = append(problems, fmt.Sprintf("keyTakeaways must have %d-%d items, but it has %d", minTakeaways, maxTakeaways, len(output.KeyTakeaways)))
+		}
+		return problems
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize blog: %w", err)
+	}
+
+	return &BlogSummary{
+		Summary:      output.Summary,
+		KeyTakeaways: output.KeyTakeaways,
+		Source:       SummarySourceAI,
+		Usage:        usage.Usage(),
+	}, nil
+}
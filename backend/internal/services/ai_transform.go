@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ai-blog-backend/internal/models"
+)
+
+// Writing assistant actions supported by TransformText
+const (
+	TransformRewrite    = "rewrite"
+	TransformExpand     = "expand"
+	TransformShorten    = "shorten"
+	TransformFixGrammar = "fix-grammar"
+)
+
+// transformContextLimit is how many characters of surrounding text are sent on each side of the selection
+const transformContextLimit = 1500
+
+type TransformTextRequest struct {
+	Action        string               `json:"action" binding:"required,oneof=rewrite expand shorten fix-grammar"`
+	Selection     string               `json:"selection" binding:"required"`
+	ContextBefore string               `json:"contextBefore"` // Text preceding the selection
+	ContextAfter  string               `json:"contextAfter"`  // Text following the selection
+	Tone          string               `json:"tone" binding:"omitempty,oneof=professional casual technical friendly"`
+	Voice         *models.VoiceProfile `json:"voice"` // Overrides the author's saved voice profile
+	Force         bool                 `json:"force"` // Skip cached responses
+}
+
+type TransformTextResponse struct {
+	Action string     `json:"action"`
+	Text   string     `json:"text"`
+	Usage  TokenUsage `json:"usage"`
+}
+
+// TransformText rewrites, expands, shortens or fixes the grammar of a passage selected in the editor
+func (s *AIService) TransformText(ctx context.Context, req TransformTextRequest) (*TransformTextResponse, error) {
+	ctx, cancel := s.withTimeout(ctx, "transform_text")
+	defer cancel()
+	ctx, usage := TrackUsage(bypassCache(ctx, req.Force))
+	tone := normalizeTone(req.Tone)
+
+	var instruction string
+	voice := req.Voice
+	selectionTokens := countTokens(s.generator.Model(), req.Selection)
+	maxTokens := selectionTokens + 200
+	switch req.Action {
+	case TransformRewrite:
+		instruction = fmt.Sprintf("Rewrite the selected passage in a %s tone. Keep its meaning and roughly its length.", tone)
+	case TransformExpand:
+		instruction = fmt.Sprintf("Expand the selected passage with more detail, explanation or examples, in a %s tone. Aim for about twice its length.", tone)
+		maxTokens = selectionTokens*3 + 200
+	case TransformShorten:
+		instruction = fmt.Sprintf("Shorten the selected passage to about half its length in a %s tone, keeping its key points.", tone)
+	case TransformFixGrammar:
+		instruction = "Fix grammar, spelling and punctuation in the selected passage. Do not change its meaning, tone or wording beyond what the corrections need."
+		voice = houseStyle(voice)
+	default:
+		return nil, fmt.Errorf("unknown transform action %q", req.Action)
+	}
+	if maxTokens > 3000 {
+		maxTokens = 3000
+	}
+
+	prompt := fmt.Sprintf(`You are helping an author edit a blog post written in markdown.
+
+%s
+
+The surrounding text is only there for context: do not repeat or change it. Keep any markdown formatting in the passage.
+Respond with only the new version of the selected passage, without quotes or commentary.
+
+Text before the selection:
+"""
+%s
+"""
+
+Selected passage:
+"""
+%s
+"""
+
+Text after the selection:
+"""
+%s
+"""`, instruction+voiceInstructions(voice), lastRunes(req.ContextBefore, transformContextLimit), req.Selection, firstRunes(req.ContextAfter, transformContextLimit))
+
+	resp, err := s.complete(ctx, prompt, maxTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform text: %w", err)
+	}
+
+	return &TransformTextResponse{
+		Action: req.Action,
+		Text:   strings.Trim(strings.TrimSpace(resp.Content), `"`),
+		Usage:  usage.Usage(),
+	}, nil
+}
+
+// firstRunes returns at most the first n characters of text
+func firstRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n])
+}
+
+// lastRunes returns at most the last n characters of text
+func lastRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[len(runes)-n:])
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ai-blog-backend/internal/models"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// TranslatedBlog is the translated text of a post, as returned by the model
+type TranslatedBlog struct {
+	Title           string `json:"title"`
+	Excerpt         string `json:"excerpt"`
+	MetaTitle       string `json:"metaTitle"`
+	MetaDescription string `json:"metaDescription"`
+	Slug            string `json:"slug"`
+	Content         string `json:"content"`
+}
+
+type TranslateBlogResponse struct {
+	Translation TranslatedBlog
+	Usage       TokenUsage
+}
+
+// translationFieldTokens is the response budget for a post's title, excerpt and SEO fields
+const translationFieldTokens = 600
+
+// TranslateBlog translates a post into locale, keeping its markdown structure intact.
+// The content is translated a few sections at a time so long posts fit the model.
+func (s *AIService) TranslateBlog(ctx context.Context, blog *models.Blog, locale string) (*TranslateBlogResponse, error) {
+	ctx, cancel := s.withTimeout(ctx, "translate_blog")
+	defer cancel()
+	ctx, usage := TrackUsage(ctx)
+
+	sourceLocale := blog.Locale
+	if sourceLocale == "" {
+		sourceLocale = "en"
+	}
+	from, to := languageName(sourceLocale), languageName(locale)
+
+	translated, err := s.translateFields(ctx, blog, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to translate blog: %w", err)
+	}
+
+	model := s.generator.Model()
+	chunks := translationChunks(model, blog.Content, translationChunkTokens(model))
+	sections := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		// Each chunk is a separate call, so stop once the author runs out of tokens
+		if err := checkQuota(ctx); err != nil {
+			return nil, err
+		}
+
+		section, err := s.translateSection(ctx, chunk, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate part %d of %d: %w", i+1, len(chunks), err)
+		}
+		sections = append(sections, section)
+	}
+	translated.Content = strings.Join(sections, "\n\n")
+
+	return &TranslateBlogResponse{Translation: *translated, Usage: usage.Usage()}, nil
+}
+
+// translateFields translates everything but the content of a post
+func (s *AIService) translateFields(ctx context.Context, blog *models.Blog, from, to string) (*TranslatedBlog, error) {
+	prompt := fmt.Sprintf(`Translate the following blog post fields from %s into %s.
+
+Translate naturally rather than word for word. Leave a field empty if it is empty in the original.
+Also write a URL slug for the translated title: lowercase words separated by hyphens, transliterated to Latin letters.
+
+Title: %s
+Excerpt: %s
+Meta title: %s
+Meta description: %s
+
+Respond with a JSON object in exactly this shape:
+{"title": "...", "excerpt": "...", "metaTitle": "...", "metaDescription": "...", "slug": "..."}`,
+		from, to, blog.Title, blog.Excerpt, blog.MetaTitle, blog.MetaDescription)
+
+	var translated TranslatedBlog
+	err := s.generateJSON(ctx, "translate blog", prompt, translationFieldTokens, &translated, func() []string {
+		translated.Title = strings.TrimSpace(translated.Title)
+		translated.Excerpt = strings.TrimSpace(translated.Excerpt)
+		translated.MetaTitle = strings.TrimSpace(translated.MetaTitle)
+		translated.MetaDescription = strings.TrimSpace(translated.MetaDescription)
+		translated.Slug = strings.TrimSpace(translated.Slug)
+
+		if translated.Title == "" {
+			return []string{"title must not be empty"}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &translated, nil
+}
+
+// translateSection translates a run of markdown, checking the headings and code
+// blocks survived
+func (s *AIService) translateSection(ctx context.Context, markdown, from, to string) (string, error) {
+	prompt := fmt.Sprintf(`Translate the following part of a blog post from %s into %s.
+
+Keep the markdown structure exactly as it is: the same headings, lists, links, images, emphasis and code blocks.
+Do not translate code, URLs or image paths.
+
+"""
+%s
+"""
+
+Respond with a JSON object in exactly this shape:
+{"content": "..."}`, from, to, markdown)
+
+	// Translations often run longer than the original
+	maxTokens := countTokens(s.generator.Model(), markdown)*2 + 100
+	if maxTokens > maxCompletionTokens {
+		maxTokens = maxCompletionTokens
+	}
+
+	sourceHeadings, sourceFences := markdownShape(markdown)
+
+	var translated struct {
+		Content string `json:"content"`
+	}
+	err := s.generateJSON(ctx, "translate blog", prompt, maxTokens, &translated, func() []string {
+		translated.Content = strings.TrimSpace(translated.Content)
+		if translated.Content == "" {
+			return []string{"content must not be empty"}
+		}
+
+		var problems []string
+		headings, fences := markdownShape(translated.Content)
+		if headings != sourceHeadings {
+			problems = append(problems, fmt.Sprintf("content must keep all %d headings of the original, but it has %d", sourceHeadings, headings))
+		}
+		if fences != sourceFences {
+			problems = append(problems, fmt.Sprintf("content must keep all %d code fences of the original, but it has %d", sourceFences, fences))
+		}
+		return problems
+	})
+	if err != nil {
+		return "", err
+	}
+	return translated.Content, nil
+}
+
+// translationChunkTokens is how much markdown to translate per call. A call holds
+// the source and the translation, which can run twice as long, and a retry holds
+// a failed translation as well.
+func translationChunkTokens(model string) int {
+	window := contextWindow(model)
+	tokens := (window - window/10 - 500) / 7
+	if tokens > maxCompletionTokens/2 {
+		tokens = maxCompletionTokens / 2
+	}
+	if tokens < 200 {
+		tokens = 200
+	}
+	return tokens
+}
+
+// translationChunks splits markdown content into runs of whole sections of up to
+// maxTokens for model, cutting sections that are too long on their own between paragraphs
+func translationChunks(model, content string, maxTokens int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentTokens = 0
+		}
+	}
+
+	for _, section := range markdownSections(content) {
+		tokens := countTokens(model, section)
+		if tokens > maxTokens {
+			flush()
+			chunks = append(chunks, splitChunks(model, section, maxTokens)...)
+			continue
+		}
+		if current.Len() > 0 && currentTokens+1+tokens > maxTokens {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+			currentTokens++
+		}
+		current.WriteString(section)
+		currentTokens += tokens
+	}
+	flush()
+	return chunks
+}
+
+// markdownSections splits markdown content before each heading outside a code block
+func markdownSections(content string) []string {
+	var sections []string
+	var current []string
+	inCode := false
+
+	flush := func() {
+		if section := strings.TrimSpace(strings.Join(current, "\n")); section != "" {
+			sections = append(sections, section)
+		}
+		current = current[:0]
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+		} else if !inCode && strings.HasPrefix(trimmed, "#") {
+			flush()
+		}
+		current = append(current, line)
+	}
+	flush()
+	return sections
+}
+
+// languageName returns the English name of a locale, e.g. "Spanish" for es
+func languageName(locale string) string {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return locale
+	}
+	if name := display.English.Tags().Name(tag); name != "" {
+		return name
+	}
+	return locale
+}
+
+// markdownShape counts the headings and code fence lines in markdown content,
+// ignoring heading-like lines inside code blocks
+func markdownShape(content string) (headings, fences int) {
+	inCode := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			fences++
+			inCode = !inCode
+			continue
+		}
+		if !inCode && strings.HasPrefix(line, "#") {
+			headings++
+		}
+	}
+	return headings, fences
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"ai-blog-backend/internal/models"
+)
+
+// VoiceRequest is an AI writing request that can be written in an author's voice
+type VoiceRequest interface {
+	// UseDefaultVoice applies the author's saved profile unless the request brings its own
+	UseDefaultVoice(voice *models.VoiceProfile)
+}
+
+func (r *GenerateContentRequest) UseDefaultVoice(voice *models.VoiceProfile) {
+	if r.Voice == nil {
+		r.Voice = voice
+	}
+}
+
+func (r *TransformTextRequest) UseDefaultVoice(voice *models.VoiceProfile) {
+	if r.Voice == nil {
+		r.Voice = voice
+	}
+}
+
+func (r *GenerateOutlineRequest) UseDefaultVoice(voice *models.VoiceProfile) {
+	if r.Voice == nil {
+		r.Voice = voice
+	}
+}
+
+func (r *GenerateFromOutlineRequest) UseDefaultVoice(voice *models.VoiceProfile) {
+	if r.Voice == nil {
+		r.Voice = voice
+	}
+}
+
+// voiceInstructions turns a voice profile into instructions to append to a prompt.
+// It returns an empty string when there is nothing to apply.
+func voiceInstructions(voice *models.VoiceProfile) string {
+	if voice.IsEmpty() {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\nWrite in the author's own voice:")
+	if voice.ReadingLevel > 0 {
+		fmt.Fprintf(&b, "\n- Write for a reading level of about grade %d.", voice.ReadingLevel)
+	}
+	for _, rule := range voice.StyleRules {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			fmt.Fprintf(&b, "\n- %s", rule)
+		}
+	}
+	if len(voice.BannedWords) > 0 {
+		fmt.Fprintf(&b, "\n- Never use these words or phrases: %s.", strings.Join(voice.BannedWords, ", "))
+	}
+	if len(voice.Samples) > 0 {
+		b.WriteString("\n- Match the style, rhythm and vocabulary of these samples of the author's writing, but not their topics:")
+		for _, sample := range voice.Samples {
+			fmt.Fprintf(&b, "\n\"\"\"\n%s\n\"\"\"", strings.TrimSpace(sample))
+		}
+	}
+	return b.String()
+}
+
+// houseStyle keeps only the parts of a voice profile that apply to corrections:
+// style rules and banned words
+func houseStyle(voice *models.VoiceProfile) *models.VoiceProfile {
+	if voice == nil {
+		return nil
+	}
+	return &models.VoiceProfile{StyleRules: voice.StyleRules, BannedWords: voice.BannedWords}
+}
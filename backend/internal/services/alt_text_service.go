@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"ai-blog-backend/internal/models"
+)
+
+const (
+	// maxAltTextImages is how many images are described in one request
+	maxAltTextImages = 20
+	// maxAltTextLength is the longest alt text we accept, in characters
+	maxAltTextLength = 150
+	// maxImageContextLength keeps the text around each image short, in characters
+	maxImageContextLength = 800
+)
+
+// ErrAltTextConflict is returned when the images to patch no longer match the content
+var ErrAltTextConflict = errors.New("the post's images have changed since the suggestions were made; request new suggestions")
+
+// emptyAltImagePattern matches markdown images with empty alt text, e.g. ![](photo.png "Title")
+var emptyAltImagePattern = regexp.MustCompile(`!\[\s*\]\(\s*(<[^>]*>|[^)\s]+)(?:\s+"[^"]*")?\s*\)`)
+
+// MissingAltImage is an image in a post's content that has no alt text. Index counts
+// only such images, in order, and identifies the image when suggestions are applied.
+type MissingAltImage struct {
+	Index   int
+	Src     string
+	Heading string // Nearest heading above the image
+	Context string // Text of the paragraphs around the image
+	start   int    // Byte offsets of the image markup in the content
+	end     int
+}
+
+// AltTextSuggestion is alt text for one image
+type AltTextSuggestion struct {
+	Index int    `json:"index" binding:"min=0"`
+	Src   string `json:"src" binding:"required"`
+	Alt   string `json:"alt" binding:"required,max=150"`
+}
+
+type AltTextSuggestionsResponse struct {
+	Images              []AltTextSuggestion `json:"images"`
+	Remaining           int                 `json:"remaining"`           // Images left for a later request
+	FeaturedImagePrompt string              `json:"featuredImagePrompt"` // Prompt for an image generator
+	Usage               TokenUsage          `json:"usage"`
+}
+
+type ApplyAltTextRequest struct {
+	Images []AltTextSuggestion `json:"images" binding:"required,min=1,dive"`
+}
+
+// AltTextService finds images without alt text in posts and fills it in
+type AltTextService struct {
+	blogService *BlogService
+	aiService   *AIService
+}
+
+func NewAltTextService(blogService *BlogService, aiService *AIService) *AltTextService {
+	return &AltTextService{blogService: blogService, aiService: aiService}
+}
+
+// Suggest proposes alt text for the post's images that have none, along with a
+// prompt for generating a featured image
+func (s *AltTextService) Suggest(ctx context.Context, blog *models.Blog) (*AltTextSuggestionsResponse, error) {
+	images := findMissingAltImages(blog.Content)
+	remaining := 0
+	if len(images) > maxAltTextImages {
+		remaining = len(images) - maxAltTextImages
+		images = images[:maxAltTextImages]
+	}
+
+	response, err := s.aiService.SuggestAltText(ctx, blog.Title, blog.Excerpt, images)
+	if err != nil {
+		return nil, err
+	}
+	response.Remaining = remaining
+	return response, nil
+}
+
+// Apply writes accepted alt text into the post's content, saving it as a new revision
+func (s *AltTextService) Apply(blogID, authorID string, req ApplyAltTextRequest) (*models.Blog, error) {
+	return s.blogService.ModifyBlog(blogID, authorID, func(blog *models.Blog) (models.CreateBlogRequest, error) {
+		content, err := applyAltText(blog.Content, req.Images)
+		if err != nil {
+			return models.CreateBlogRequest{}, err
+		}
+
+		return models.CreateBlogRequest{
+			Title:           blog.Title,
+			Content:         content,
+			Description:     blog.Excerpt,
+			Tags:            blog.Tags,
+			Status:          blog.Status,
+			ScheduledAt:     blog.ScheduledAt,
+			MetaTitle:       blog.MetaTitle,
+			MetaDescription: blog.MetaDescription,
+			FeaturedImage:   blog.FeaturedImage,
+		}, nil
+	})
+}
+
+// applyAltText inserts alt text into the images it names. Every image must still be
+// at its index with the same source, otherwise nothing is changed.
+func applyAltText(content string, suggestions []AltTextSuggestion) (string, error) {
+	images := findMissingAltImages(content)
+
+	alts := make(map[int]string, len(suggestions))
+	for _, suggestion := range suggestions {
+		if suggestion.Index >= len(images) || images[suggestion.Index].Src != suggestion.Src {
+			return "", ErrAltTextConflict
+		}
+		alts[suggestion.Index] = suggestion.Alt
+	}
+
+	// Patch from the end so earlier offsets stay valid
+	for i := len(images) - 1; i >= 0; i-- {
+		alt, ok := alts[i]
+		if !ok {
+			continue
+		}
+		image := images[i]
+		markup := content[image.start:image.end]
+		patched := "![" + escapeAltText(alt) + markup[strings.Index(markup, "]"):]
+		content = content[:image.start] + patched + content[image.end:]
+	}
+	return content, nil
+}
+
+// escapeAltText makes alt text safe to put between markdown image brackets
+func escapeAltText(alt string) string {
+	alt = strings.Join(strings.Fields(alt), " ")
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(alt)
+}
+
+// findMissingAltImages returns the images with empty alt text in markdown content,
+// skipping fenced code blocks
+func findMissingAltImages(content string) []MissingAltImage {
+	paragraphs := strings.Split(content, "\n\n")
+
+	var images []MissingAltImage
+	offset := 0
+	inCode := false
+	heading := ""
+	for i, paragraph := range paragraphs {
+		trimmed := strings.TrimSpace(paragraph)
+		fences := strings.Count(trimmed, "```")
+		switch {
+		case inCode || strings.HasPrefix(trimmed, "```"):
+		case strings.HasPrefix(trimmed, "#"):
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		default:
+			for _, match := range emptyAltImagePattern.FindAllStringSubmatchIndex(paragraph, -1) {
+				images = append(images, MissingAltImage{
+					Index:   len(images),
+					Src:     strings.Trim(paragraph[match[2]:match[3]], "<>"),
+					Heading: heading,
+					Context: imageContext(paragraphs, i),
+					start:   offset + match[0],
+					end:     offset + match[1],
+				})
+			}
+		}
+		if fences%2 == 1 {
+			inCode = !inCode
+		}
+		offset += len(paragraph) + len("\n\n")
+	}
+	return images
+}
+
+// imageContext returns the plain text of the paragraph at i and its neighbours
+func imageContext(paragraphs []string, i int) string {
+	var parts []string
+	for j := i - 1; j <= i+1; j++ {
+		if j < 0 || j >= len(paragraphs) {
+			continue
+		}
+		if text := plainText(paragraphs[j]); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return truncateText(strings.Join(parts, " "), maxImageContextLength)
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"ai-blog-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// askCandidatePosts is how many of the best matching posts are split into passages
+	askCandidatePosts = 5
+	// askMaxPassages is how many passages are given to the model as sources
+	askMaxPassages = 6
+	// maxPassageLength keeps each source passage short, in characters
+	maxPassageLength = 1200
+)
+
+// AskUsageAccount is the usage ledger account that anonymous questions are recorded under
+const AskUsageAccount = "system:ask"
+
+type AskRequest struct {
+	Question string `json:"question" binding:"required,max=500"`
+}
+
+// AskCitation points at the paragraph of a post that an answer relies on.
+// Anchors are p-1, p-2, ... counting the post's markdown paragraphs, with each
+// fenced code block counted once.
+type AskCitation struct {
+	Slug   string `json:"slug"`
+	Title  string `json:"title"`
+	Anchor string `json:"anchor"`
+}
+
+type AskResponse struct {
+	Answer    string        `json:"answer"`
+	Answered  bool          `json:"answered"` // False when the blog doesn't cover the question
+	Citations []AskCitation `json:"citations"`
+	Usage     TokenUsage    `json:"usage"`
+}
+
+// AskPassage is one paragraph of a published post, given to the model as a source
+type AskPassage struct {
+	Slug    string
+	Title   string
+	Anchor  string
+	Heading string // Nearest heading above the paragraph
+	Text    string
+	score   int
+}
+
+// AskService answers reader questions using only the published posts
+type AskService struct {
+	db        *gorm.DB
+	aiService *AIService
+}
+
+func NewAskService(db *gorm.DB, aiService *AIService) *AskService {
+	return &AskService{db: db, aiService: aiService}
+}
+
+// Ask retrieves the passages most relevant to a question and answers from them.
+// Questions with no relevant passages are refused without calling the model.
+func (s *AskService) Ask(ctx context.Context, req AskRequest) (*AskResponse, error) {
+	terms := questionTerms(req.Question)
+	if len(terms) == 0 {
+		return refusal(), nil
+	}
+
+	passages, err := s.findPassages(terms)
+	if err != nil {
+		return nil, err
+	}
+	if len(passages) == 0 {
+		return refusal(), nil
+	}
+
+	return s.aiService.AnswerQuestion(ctx, req.Question, passages)
+}
+
+// findPassages runs a full-text search for posts matching any of the terms and
+// returns their paragraphs that share the most terms with the question
+func (s *AskService) findPassages(terms []string) ([]AskPassage, error) {
+	var blogs []models.Blog
+	err := s.db.Raw(`SELECT blogs.*
+		FROM blogs, to_tsquery('english', ?) AS q
+		WHERE blogs.deleted_at IS NULL
+			AND blogs.status = 'published'
+			AND blogs.search_vector @@ q
+		ORDER BY ts_rank_cd(blogs.search_vector, q) DESC
+		LIMIT ?`, strings.Join(terms, " | "), askCandidatePosts).Scan(&blogs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var passages []AskPassage
+	for _, blog := range blogs {
+		for _, passage := range splitPassages(blog) {
+			passage.score = termOverlap(passage.Heading+" "+passage.Text, terms)
+			if passage.score > 0 {
+				passages = append(passages, passage)
+			}
+		}
+	}
+
+	sort.SliceStable(passages, func(i, j int) bool {
+		return passages[i].score > passages[j].score
+	})
+	if len(passages) > askMaxPassages {
+		passages = passages[:askMaxPassages]
+	}
+	return passages, nil
+}
+
+// splitPassages splits a post into its markdown paragraphs, skipping headings,
+// code blocks and very short paragraphs but keeping their place in the anchor
+// numbering. A fenced code block counts once, however many blank lines it has.
+func splitPassages(blog models.Blog) []AskPassage {
+	var passages []AskPassage
+	heading := blog.Title
+	block := 0
+	inCode := false
+	for _, paragraph := range strings.Split(blog.Content, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		code := inCode || strings.HasPrefix(paragraph, "```")
+		if !inCode {
+			block++
+		}
+		if strings.Count(paragraph, "```")%2 == 1 {
+			inCode = !inCode
+		}
+
+		switch {
+		case code:
+			continue
+		case strings.HasPrefix(paragraph, "#"):
+			heading = strings.TrimSpace(strings.TrimLeft(paragraph, "#"))
+			continue
+		case len(paragraph) < 40:
+			continue
+		}
+
+		passages = append(passages, AskPassage{
+			Slug:    blog.Slug,
+			Title:   blog.Title,
+			Anchor:  fmt.Sprintf("p-%d", block),
+			Heading: heading,
+			Text:    firstRunes(paragraph, maxPassageLength),
+		})
+	}
+	return passages
+}
+
+// questionTerms returns the distinct words of a question worth searching for.
+// Only letters and digits survive, so the terms are safe to join into a tsquery.
+func questionTerms(question string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range wordsOf(question) {
+		if len([]rune(word)) < 3 || stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// termOverlap counts how many of the terms appear in text, ignoring simple word endings
+func termOverlap(text string, terms []string) int {
+	words := make(map[string]bool)
+	for _, word := range wordsOf(text) {
+		words[stemWord(word)] = true
+	}
+
+	count := 0
+	for _, term := range terms {
+		if words[stemWord(term)] {
+			count++
+		}
+	}
+	return count
+}
+
+// stemWord strips common English suffixes so "generics" matches "generic"
+func stemWord(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if len(word) > len(suffix)+3 && strings.HasSuffix(word, suffix) {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// stopWords are common words that say little about what a question or text is about
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "what": true,
+	"how": true, "why": true, "who": true, "when": true, "where": true, "which": true,
+	"does": true, "did": true, "can": true, "could": true, "should": true, "would": true,
+	"you": true, "your": true, "with": true, "this": true, "that": true, "there": true,
+	"about": true, "from": true, "have": true, "has": true, "any": true, "some": true,
+	"into": true, "use": true, "get": true, "best": true, "way": true,
+}
+
+func refusal() *AskResponse {
+	return &AskResponse{
+		Answer:    "Sorry, I couldn't find anything on the blog that answers that question.",
+		Answered:  false,
+		Citations: []AskCitation{},
+	}
+}
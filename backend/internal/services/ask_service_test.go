@@ -0,0 +1,43 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"ai-blog-backend/internal/models"
+)
+
+func TestSplitPassages(t *testing.T) {
+	long := strings.Repeat("A paragraph long enough to cite. ", 2)
+	tests := []struct {
+		name        string
+		content     string
+		wantAnchors []string
+	}{
+		{name: "paragraphs", content: long + "\n\n" + long, wantAnchors: []string{"p-1", "p-2"}},
+		{name: "headings and short paragraphs keep their place", content: "## Setup\n\nShort.\n\n" + long, wantAnchors: []string{"p-3"}},
+		{
+			name:        "code block with blank lines counts once",
+			content:     long + "\n\n```go\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {}\n```\n\n" + long,
+			wantAnchors: []string{"p-1", "p-3"},
+		},
+		{
+			name:        "prose inside a code block isn't a passage",
+			content:     "```\n\n" + long + "\n\n```\n\n" + long,
+			wantAnchors: []string{"p-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var anchors []string
+			for _, passage := range splitPassages(models.Blog{Title: "Post", Slug: "post", Content: tt.content}) {
+				anchors = append(anchors, passage.Anchor)
+			}
+			if !reflect.DeepEqual(anchors, tt.wantAnchors) {
+				t.Errorf("anchors = %v, want %v", anchors, tt.wantAnchors)
+			}
+		})
+	}
+}
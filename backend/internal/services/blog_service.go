@@ -1,24 +1,62 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"html"
+	"log"
 	"strings"
 	"time"
+	"unicode"
 
 	"ai-blog-backend/internal/models"
 
 	"github.com/lib/pq"
+	"golang.org/x/text/language"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// searchVectorSQL builds the weighted tsvector stored in blogs.search_vector.
+// Titles rank highest, followed by tags, the excerpt and finally the body.
+const searchVectorSQL = `setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(array_to_string(tags, ' '), '')), 'B') ||
+	setweight(to_tsvector('english', coalesce(excerpt, '')), 'C') ||
+	setweight(to_tsvector('english', coalesce(content, '')), 'D')`
+
+// excerptLength is the maximum length of a generated excerpt, in characters
+const excerptLength = 200
+
+// ErrInvalidSchedule is returned when a scheduled blog has no publish time in the future
+var ErrInvalidSchedule = errors.New("scheduled blogs need a scheduledAt time in the future")
+
+// ErrInvalidLocale is returned for locales that aren't valid BCP 47 language tags
+var ErrInvalidLocale = errors.New("locale must be a language tag such as en, es or pt-BR")
+
+// ErrTranslationExists is returned when a blog already has a translation into a locale
+var ErrTranslationExists = errors.New("a translation into this locale already exists")
+
+// SaveListener is called in the background after a blog is created, updated or published
+type SaveListener func(blog models.Blog)
+
 type BlogService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	listeners []SaveListener
 }
 
 func NewBlogService(db *gorm.DB) *BlogService {
 	return &BlogService{db: db}
 }
 
+// AddSaveListener registers a function to run after every save.
+// Listeners must be added before the service starts handling requests.
+func (s *BlogService) AddSaveListener(listener SaveListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
 func (s *BlogService) GetBlogs(page, limit int, status string) ([]models.Blog, int64, error) {
 	var blogs []models.Blog
 	var total int64
@@ -31,6 +69,9 @@ func (s *BlogService) GetBlogs(page, limit int, status string) ([]models.Blog, i
 	}
 	// Otherwise, show ALL blogs regardless of status
 
+	// Scheduled blogs stay hidden until the scheduler publishes them
+	query = query.Where("status <> ?", "scheduled")
+
 	// Get total count
 	query.Count(&total)
 
@@ -41,6 +82,66 @@ func (s *BlogService) GetBlogs(page, limit int, status string) ([]models.Blog, i
 	return blogs, total, err
 }
 
+// Highlight delimiters for ts_headline. They're private-use characters, which are
+// removed from the text first, so matches can be marked after HTML-escaping it.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+// highlightHTML HTML-escapes a ts_headline fragment and wraps its matches in <mark>
+func highlightHTML(fragment string) string {
+	fragment = html.EscapeString(fragment)
+	fragment = strings.ReplaceAll(fragment, highlightStart, "<mark>")
+	return strings.ReplaceAll(fragment, highlightStop, "</mark>")
+}
+
+// SearchBlogs runs a full-text search over published blogs, ordered by weighted rank.
+// Titles and snippets are returned as HTML, with matches wrapped in <mark>.
+func (s *BlogService) SearchBlogs(query string, page, limit int) ([]models.BlogSearchResult, int64, error) {
+	var results []models.BlogSearchResult
+	var total int64
+
+	err := s.db.Model(&models.Blog{}).
+		Where("status = ?", "published").
+		Where("search_vector @@ websearch_to_tsquery('english', ?)", query).
+		Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	delimiters := highlightStart + highlightStop
+	selectors := fmt.Sprintf("StartSel=%s, StopSel=%s", highlightStart, highlightStop)
+	err = s.db.Raw(`SELECT blogs.*,
+			ts_rank_cd(blogs.search_vector, q) AS rank,
+			ts_headline('english', translate(blogs.title, ?, ''), q, ?) AS title_highlight,
+			ts_headline('english', translate(blogs.content, ?, ''), q, ?) AS snippet
+		FROM blogs, websearch_to_tsquery('english', ?) AS q
+		WHERE blogs.deleted_at IS NULL
+			AND blogs.status = 'published'
+			AND blogs.search_vector @@ q
+		ORDER BY rank DESC, blogs.published_at DESC
+		LIMIT ? OFFSET ?`,
+		delimiters, "HighlightAll=true, "+selectors,
+		delimiters, "MaxFragments=2, MaxWords=35, MinWords=15, "+selectors,
+		query, limit, offset).Scan(&results).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range results {
+		results[i].TitleHighlight = highlightHTML(results[i].TitleHighlight)
+		results[i].Snippet = highlightHTML(results[i].Snippet)
+	}
+	return results, total, nil
+}
+
+// RebuildSearchIndex fills in search vectors for blogs that don't have one yet
+func (s *BlogService) RebuildSearchIndex() error {
+	return s.db.Exec("UPDATE blogs SET search_vector = " + searchVectorSQL + " WHERE search_vector IS NULL").Error
+}
+
 func (s *BlogService) GetBlogByID(id string) (*models.Blog, error) {
 	var blog models.Blog
 	err := s.db.Where("id = ?", id).First(&blog).Error
@@ -64,6 +165,15 @@ func (s *BlogService) GetBlogBySlug(slug string) (*models.Blog, error) {
 		return nil, err
 	}
 
+	if blog.TranslationGroupID != nil {
+		err = s.db.Model(&models.Blog{}).Select("locale, slug, title").
+			Where("translation_group_id = ? AND id <> ? AND status = ?", *blog.TranslationGroupID, blog.ID, "published").
+			Order("locale").Scan(&blog.Translations).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Increment view count asynchronously to avoid blocking the response
 	go func() {
 		s.db.Model(&models.Blog{}).Where("id = ?", blog.ID).
@@ -73,27 +183,52 @@ func (s *BlogService) GetBlogBySlug(slug string) (*models.Blog, error) {
 	return &blog, nil
 }
 
+// ResolveSlugAlias returns the current slug of the blog that used to be published under slug
+func (s *BlogService) ResolveSlugAlias(slug string) (string, error) {
+	var alias models.SlugAlias
+	err := s.db.Where("slug = ?", slug).First(&alias).Error
+	if err != nil {
+		return "", err
+	}
+
+	var blog models.Blog
+	err = s.db.Select("slug").Where("id = ?", alias.BlogID).First(&blog).Error
+	if err != nil {
+		return "", err
+	}
+
+	return blog.Slug, nil
+}
+
+// GetAuthorBlog returns one of the author's blogs without counting a view
+func (s *BlogService) GetAuthorBlog(id, authorID string) (*models.Blog, error) {
+	var blog models.Blog
+	err := s.db.Where("id = ? AND author_id = ?", id, authorID).First(&blog).Error
+	if err != nil {
+		return nil, err
+	}
+	return &blog, nil
+}
+
 func (s *BlogService) GetUserDrafts(authorID string) ([]models.Blog, error) {
 	var blogs []models.Blog
-	err := s.db.Where("author_id = ? AND status IN ?", authorID, []string{"draft", "published"}).
+	err := s.db.Where("author_id = ? AND status IN ?", authorID, []string{"draft", "scheduled", "published"}).
 		Order("updated_at DESC").Find(&blogs).Error
 	return blogs, err
 }
 
 func (s *BlogService) CreateDraft(req models.CreateBlogRequest, authorID, authorName, authorEmail string) (*models.Blog, error) {
-	slug := s.generateSlug(req.Title)
-
-	// Ensure slug is unique
-	originalSlug := slug
-	counter := 1
-	for {
-		var existingBlog models.Blog
-		err := s.db.Where("slug = ?", slug).First(&existingBlog).Error
-		if err == gorm.ErrRecordNotFound {
-			break
+	locale := "en"
+	if req.Locale != "" {
+		var err error
+		if locale, err = NormalizeLocale(req.Locale); err != nil {
+			return nil, err
 		}
-		slug = fmt.Sprintf("%s-%d", originalSlug, counter)
-		counter++
+	}
+
+	slug, err := s.uniqueSlug(s.db, s.generateSlug(req.Title), "")
+	if err != nil {
+		return nil, err
 	}
 
 	blog := &models.Blog{
@@ -109,46 +244,234 @@ func (s *BlogService) CreateDraft(req models.CreateBlogRequest, authorID, author
 		MetaTitle:       req.MetaTitle,
 		MetaDescription: req.MetaDescription,
 		FeaturedImage:   req.FeaturedImage,
+		Outline:         req.Outline,
+		Locale:          locale,
 	}
 
-	if req.Status == "published" {
-		now := time.Now()
-		blog.PublishedAt = &now
+	if err := s.applyStatus(blog, req); err != nil {
+		return nil, err
 	}
 
-	err := s.db.Create(blog).Error
-	return blog, err
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(blog).Error; err != nil {
+			return err
+		}
+
+		if err := refreshSearchVector(tx, blog.ID); err != nil {
+			return err
+		}
+
+		return snapshotRevision(tx, blog)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifySaved(blog)
+	return blog, nil
 }
 
 func (s *BlogService) UpdateBlog(id string, req models.CreateBlogRequest, authorID string) (*models.Blog, error) {
+	return s.ModifyBlog(id, authorID, func(*models.Blog) (models.CreateBlogRequest, error) {
+		return req, nil
+	})
+}
+
+// ModifyBlog updates a blog with the changes edit derives from its current state.
+// The row stays locked from reading it to saving, so no concurrent save is lost.
+func (s *BlogService) ModifyBlog(id, authorID string, edit func(blog *models.Blog) (models.CreateBlogRequest, error)) (*models.Blog, error) {
 	var blog models.Blog
-	err := s.db.Where("id = ? AND author_id = ?", id, authorID).First(&blog).Error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the row so concurrent saves get sequential revision numbers
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND author_id = ?", id, authorID).First(&blog).Error
+		if err != nil {
+			return err
+		}
+
+		req, err := edit(&blog)
+		if err != nil {
+			return err
+		}
+
+		// Update slug if title changed, keeping the old one as a redirect
+		if blog.Title != req.Title {
+			slug, err := s.uniqueSlug(tx, s.generateSlug(req.Title), blog.ID)
+			if err != nil {
+				return err
+			}
+			if slug != blog.Slug {
+				if err := retireSlug(tx, blog.ID, blog.Slug, slug); err != nil {
+					return err
+				}
+				blog.Slug = slug
+			}
+		}
+
+		blog.Title = req.Title
+		blog.Content = req.Content
+		blog.Excerpt = s.generateExcerpt(req.Content, req.Description)
+		blog.Tags = pq.StringArray(req.Tags)
+		blog.MetaTitle = req.MetaTitle
+		blog.MetaDescription = req.MetaDescription
+		blog.FeaturedImage = req.FeaturedImage
+		if req.Outline != nil {
+			blog.Outline = req.Outline
+		}
+
+		if err := s.applyStatus(&blog, req); err != nil {
+			return err
+		}
+
+		if err := tx.Save(&blog).Error; err != nil {
+			return err
+		}
+
+		if err := refreshSearchVector(tx, blog.ID); err != nil {
+			return err
+		}
+
+		return snapshotRevision(tx, &blog)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Update slug if title changed
-	if blog.Title != req.Title {
-		blog.Slug = s.generateSlug(req.Title)
+	s.notifySaved(&blog)
+	return &blog, nil
+}
+
+// FindTranslation returns the blog's translation into locale, or gorm.ErrRecordNotFound
+func (s *BlogService) FindTranslation(blog *models.Blog, locale string) (*models.Blog, error) {
+	if blog.TranslationGroupID == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var translation models.Blog
+	err := s.db.Where("translation_group_id = ? AND locale = ?", *blog.TranslationGroupID, locale).
+		First(&translation).Error
+	if err != nil {
+		return nil, err
 	}
+	return &translation, nil
+}
+
+// CreateTranslation saves a translation of one of the author's blogs as a new
+// draft in locale, linking both posts into the same translation group
+func (s *BlogService) CreateTranslation(sourceID, authorID, locale string, translated TranslatedBlog) (*models.Blog, error) {
+	var blog *models.Blog
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var source models.Blog
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND author_id = ?", sourceID, authorID).First(&source).Error
+		if err != nil {
+			return err
+		}
 
-	blog.Title = req.Title
-	blog.Content = req.Content
-	blog.Excerpt = s.generateExcerpt(req.Content, req.Description)
-	blog.Tags = pq.StringArray(req.Tags)
-	blog.MetaTitle = req.MetaTitle
-	blog.MetaDescription = req.MetaDescription
-	blog.FeaturedImage = req.FeaturedImage
+		// The first translation makes the source the head of a new group
+		if source.TranslationGroupID == nil {
+			source.TranslationGroupID = &source.ID
+			err := tx.Model(&source).UpdateColumn("translation_group_id", source.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var existing int64
+		err = tx.Model(&models.Blog{}).
+			Where("translation_group_id = ? AND locale = ?", *source.TranslationGroupID, locale).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 || source.Locale == locale {
+			return ErrTranslationExists
+		}
+
+		slug := strings.Trim(s.generateSlug(translated.Slug), "-")
+		if slug == "" {
+			slug = strings.Trim(s.generateSlug(translated.Title), "-")
+		}
+		if slug == "" {
+			// Titles in non-Latin scripts have nothing left after slugging
+			slug = source.Slug + "-" + strings.ToLower(locale)
+		}
+		slug, err = s.uniqueSlug(tx, slug, "")
+		if err != nil {
+			return err
+		}
+
+		blog = &models.Blog{
+			Title:              translated.Title,
+			Content:            translated.Content,
+			Excerpt:            s.generateExcerpt(translated.Content, translated.Excerpt),
+			Slug:               slug,
+			AuthorID:           source.AuthorID,
+			AuthorName:         source.AuthorName,
+			AuthorEmail:        source.AuthorEmail,
+			Status:             "draft",
+			Tags:               source.Tags,
+			MetaTitle:          translated.MetaTitle,
+			MetaDescription:    translated.MetaDescription,
+			FeaturedImage:      source.FeaturedImage,
+			Locale:             locale,
+			TranslationGroupID: source.TranslationGroupID,
+		}
 
-	// Handle status change to published
-	if blog.Status != "published" && req.Status == "published" {
-		now := time.Now()
-		blog.PublishedAt = &now
+		if err := tx.Create(blog).Error; err != nil {
+			return err
+		}
+
+		if err := refreshSearchVector(tx, blog.ID); err != nil {
+			return err
+		}
+
+		return snapshotRevision(tx, blog)
+	})
+	if err != nil {
+		return nil, err
 	}
-	blog.Status = req.Status
 
-	err = s.db.Save(&blog).Error
-	return &blog, err
+	s.notifySaved(blog)
+	return blog, nil
+}
+
+// PublishDueBlogs publishes every scheduled blog whose publish time has passed
+// and returns their IDs. The status check in the WHERE clause is re-evaluated
+// after row locks are taken, so when several replicas run this concurrently
+// each blog is promoted by exactly one of them.
+func (s *BlogService) PublishDueBlogs() ([]string, error) {
+	var ids []string
+	err := s.db.Raw(`UPDATE blogs
+		SET status = 'published', published_at = scheduled_at, updated_at = NOW()
+		WHERE status = 'scheduled' AND scheduled_at <= NOW() AND deleted_at IS NULL
+		RETURNING id`).Scan(&ids).Error
+	if err != nil || len(ids) == 0 || len(s.listeners) == 0 {
+		return ids, err
+	}
+
+	var blogs []models.Blog
+	if err := s.db.Where("id IN ?", ids).Find(&blogs).Error; err != nil {
+		log.Printf("Warning: Could not load published blogs for save listeners: %v", err)
+	}
+	for i := range blogs {
+		s.notifySaved(&blogs[i])
+	}
+	return ids, nil
+}
+
+// SaveOutline stores the AI outline on one of the author's blogs
+func (s *BlogService) SaveOutline(id, authorID string, outline models.BlogOutline) error {
+	result := s.db.Model(&models.Blog{}).
+		Where("id = ? AND author_id = ?", id, authorID).
+		Update("outline", outline)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 func (s *BlogService) DeleteBlog(id, authorID string) error {
@@ -162,8 +485,81 @@ func (s *BlogService) DeleteBlog(id, authorID string) error {
 	return nil
 }
 
+// notifySaved runs the save listeners for a blog in the background
+func (s *BlogService) notifySaved(blog *models.Blog) {
+	for _, listener := range s.listeners {
+		go listener(*blog)
+	}
+}
+
+// applyStatus moves a blog to the requested status, keeping its publish and schedule times consistent
+func (s *BlogService) applyStatus(blog *models.Blog, req models.CreateBlogRequest) error {
+	switch req.Status {
+	case "scheduled":
+		if req.ScheduledAt == nil || !req.ScheduledAt.After(time.Now()) {
+			return ErrInvalidSchedule
+		}
+		blog.ScheduledAt = req.ScheduledAt
+		blog.PublishedAt = nil
+	case "published":
+		// Handle status change to published
+		if blog.Status != "published" {
+			now := time.Now()
+			blog.PublishedAt = &now
+		}
+		blog.ScheduledAt = nil
+	default:
+		blog.ScheduledAt = nil
+	}
+
+	blog.Status = req.Status
+	return nil
+}
+
+// uniqueSlug appends a numeric suffix to slug until no other blog uses it,
+// either as its current slug or as a retired one. blogID is the blog being
+// saved (empty for new blogs), so it never conflicts with itself.
+func (s *BlogService) uniqueSlug(db *gorm.DB, slug, blogID string) (string, error) {
+	// Soft-deleted blogs still hold their slug in the unique index
+	blogQuery := db.Unscoped().Model(&models.Blog{}).Where("slug = ? OR slug LIKE ?", slug, slug+"-%")
+	aliasQuery := db.Model(&models.SlugAlias{}).Where("slug = ? OR slug LIKE ?", slug, slug+"-%")
+	if blogID != "" {
+		blogQuery = blogQuery.Where("id <> ?", blogID)
+		aliasQuery = aliasQuery.Where("blog_id <> ?", blogID)
+	}
+
+	var taken, retired []string
+	if err := blogQuery.Pluck("slug", &taken).Error; err != nil {
+		return "", err
+	}
+	if err := aliasQuery.Pluck("slug", &retired).Error; err != nil {
+		return "", err
+	}
+	return firstFreeSlug(slug, append(taken, retired...)), nil
+}
+
+// firstFreeSlug returns slug, or slug with the lowest numeric suffix, if it isn't taken
+func firstFreeSlug(slug string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, t := range taken {
+		used[t] = true
+	}
+
+	candidate := slug
+	for counter := 1; used[candidate]; counter++ {
+		candidate = fmt.Sprintf("%s-%d", slug, counter)
+	}
+	return candidate
+}
+
 func (s *BlogService) generateSlug(title string) string {
-	slug := strings.ToLower(title)
+	// Fold accented letters to their base letter (é -> e) so translated titles keep readable slugs
+	slug, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), title)
+	if err != nil {
+		slug = title
+	}
+
+	slug = strings.ToLower(slug)
 	slug = strings.ReplaceAll(slug, " ", "-")
 	// Remove special characters
 	var result strings.Builder
@@ -176,16 +572,44 @@ func (s *BlogService) generateSlug(title string) string {
 }
 
 func (s *BlogService) generateExcerpt(content, description string) string {
-	if description != "" {
-		if len(description) > 200 {
-			return description[:200] + "..."
+	if description = strings.TrimSpace(description); description != "" {
+		return truncateText(description, excerptLength)
+	}
+
+	// Generate from the content's text, without markdown syntax or headings
+	var paragraphs []string
+	for _, block := range markdownBlocks(content) {
+		if !block.Heading {
+			paragraphs = append(paragraphs, block.Text)
 		}
-		return description
 	}
+	return truncateText(strings.Join(paragraphs, " "), excerptLength)
+}
 
-	// Generate from content
-	if len(content) > 200 {
-		return content[:200] + "..."
+// NormalizeLocale validates a BCP 47 language tag and returns its canonical form
+func NormalizeLocale(locale string) (string, error) {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil || tag == language.Und {
+		return "", ErrInvalidLocale
 	}
-	return content
+	return tag.String(), nil
+}
+
+// refreshSearchVector recomputes the full-text search vector for a single blog
+func refreshSearchVector(tx *gorm.DB, id string) error {
+	return tx.Exec("UPDATE blogs SET search_vector = "+searchVectorSQL+" WHERE id = ?", id).Error
+}
+
+// retireSlug keeps oldSlug as a redirect to the blog and releases newSlug if
+// the blog is taking back one of its own retired slugs
+func retireSlug(tx *gorm.DB, blogID, oldSlug, newSlug string) error {
+	if oldSlug != "" {
+		alias := models.SlugAlias{Slug: oldSlug, BlogID: blogID}
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&alias).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Where("slug = ? AND blog_id = ?", newSlug, blogID).Delete(&models.SlugAlias{}).Error
 }
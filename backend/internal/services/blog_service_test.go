@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ai-blog-backend/internal/models"
+)
+
+func TestApplyStatus(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	published := now.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name            string
+		blog            models.Blog
+		req             models.CreateBlogRequest
+		wantErr         error
+		wantStatus      string
+		wantScheduledAt *time.Time
+		wantPublishedAt *time.Time
+		publishesNow    bool // PublishedAt is set to the current time
+	}{
+		{
+			name:       "draft",
+			blog:       models.Blog{Status: "draft"},
+			req:        models.CreateBlogRequest{Status: "draft"},
+			wantStatus: "draft",
+		},
+		{
+			name:         "publish a draft",
+			blog:         models.Blog{Status: "draft"},
+			req:          models.CreateBlogRequest{Status: "published"},
+			wantStatus:   "published",
+			publishesNow: true,
+		},
+		{
+			name:            "save a published blog",
+			blog:            models.Blog{Status: "published", PublishedAt: &published},
+			req:             models.CreateBlogRequest{Status: "published"},
+			wantStatus:      "published",
+			wantPublishedAt: &published,
+		},
+		{
+			name:            "schedule",
+			blog:            models.Blog{Status: "draft"},
+			req:             models.CreateBlogRequest{Status: "scheduled", ScheduledAt: &future},
+			wantStatus:      "scheduled",
+			wantScheduledAt: &future,
+		},
+		{
+			name:            "schedule a published blog",
+			blog:            models.Blog{Status: "published", PublishedAt: &published},
+			req:             models.CreateBlogRequest{Status: "scheduled", ScheduledAt: &future},
+			wantStatus:      "scheduled",
+			wantScheduledAt: &future,
+		},
+		{
+			name:            "publish a scheduled blog early",
+			blog:            models.Blog{Status: "scheduled", ScheduledAt: &future},
+			req:             models.CreateBlogRequest{Status: "published"},
+			wantStatus:      "published",
+			wantScheduledAt: nil,
+			publishesNow:    true,
+		},
+		{
+			name:       "unschedule",
+			blog:       models.Blog{Status: "scheduled", ScheduledAt: &future},
+			req:        models.CreateBlogRequest{Status: "draft"},
+			wantStatus: "draft",
+		},
+		{
+			name:    "schedule in the past",
+			blog:    models.Blog{Status: "draft"},
+			req:     models.CreateBlogRequest{Status: "scheduled", ScheduledAt: &past},
+			wantErr: ErrInvalidSchedule,
+		},
+		{
+			name:    "schedule without a time",
+			blog:    models.Blog{Status: "draft"},
+			req:     models.CreateBlogRequest{Status: "scheduled"},
+			wantErr: ErrInvalidSchedule,
+		},
+	}
+
+	s := &BlogService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blog := tt.blog
+			err := s.applyStatus(&blog, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("applyStatus() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if blog.Status != tt.blog.Status {
+					t.Errorf("Status changed to %q on error", blog.Status)
+				}
+				return
+			}
+
+			if blog.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", blog.Status, tt.wantStatus)
+			}
+			if blog.ScheduledAt != tt.wantScheduledAt {
+				t.Errorf("ScheduledAt = %v, want %v", blog.ScheduledAt, tt.wantScheduledAt)
+			}
+			if tt.publishesNow {
+				if blog.PublishedAt == nil || blog.PublishedAt.Before(now) {
+					t.Errorf("PublishedAt = %v, want the current time", blog.PublishedAt)
+				}
+			} else if blog.PublishedAt != tt.wantPublishedAt {
+				t.Errorf("PublishedAt = %v, want %v", blog.PublishedAt, tt.wantPublishedAt)
+			}
+		})
+	}
+}
+
+func TestFirstFreeSlug(t *testing.T) {
+	tests := []struct {
+		slug  string
+		taken []string
+		want  string
+	}{
+		{slug: "go-tips", taken: nil, want: "go-tips"},
+		{slug: "go-tips", taken: []string{"go-tips"}, want: "go-tips-1"},
+		{slug: "go-tips", taken: []string{"go-tips", "go-tips-1", "go-tips-2"}, want: "go-tips-3"},
+		{slug: "go-tips", taken: []string{"go-tips", "go-tips-2"}, want: "go-tips-1"},
+		{slug: "go-tips", taken: []string{"go-tips-1", "go-tips-and-tricks"}, want: "go-tips"},
+		{slug: "go-tips", taken: []string{"go-tips", "go-tips", "go-tips-1"}, want: "go-tips-2"},
+	}
+
+	for _, tt := range tests {
+		if got := firstFreeSlug(tt.slug, tt.taken); got != tt.want {
+			t.Errorf("firstFreeSlug(%q, %q) = %q, want %q", tt.slug, tt.taken, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Sources of a moderation score
+const (
+	ModeratedByAI        = "ai"
+	ModeratedByHeuristic = "heuristic"
+)
+
+// moderationTimeout bounds how long posting a comment waits for the AI classifier
+const moderationTimeout = 10 * time.Second
+
+// CommentModerationInput is a new comment along with the post it was left on
+type CommentModerationInput struct {
+	Content     string
+	AuthorName  string
+	AuthorEmail string
+	BlogTitle   string
+	BlogExcerpt string
+	BlogTags    []string
+}
+
+// ModerationResult scores a comment from 0 (clean) to 1 (certainly spam, toxic or off-topic)
+type ModerationResult struct {
+	Score   float64
+	Reasons []string
+	Source  string // ai or heuristic
+}
+
+// CommentClassifier scores comments for spam, toxicity and off-topic content
+type CommentClassifier interface {
+	Classify(ctx context.Context, input CommentModerationInput) (*ModerationResult, error)
+}
+
+// CommentModerator decides the initial status of new comments from their score.
+// Comments scoring below ApproveBelow are approved, those at or above RejectFrom
+// are rejected, and the rest are left pending for a human.
+type CommentModerator struct {
+	classifier   CommentClassifier
+	approveBelow float64
+	rejectFrom   float64
+}
+
+func NewCommentModerator(classifier CommentClassifier, approveBelow, rejectFrom float64) *CommentModerator {
+	return &CommentModerator{
+		classifier:   classifier,
+		approveBelow: approveBelow,
+		rejectFrom:   rejectFrom,
+	}
+}
+
+// Moderate scores a comment and returns the status it should start in. If the
+// classifier fails, the comment is scored by the heuristic classifier instead.
+// Since a comment can try to talk the AI classifier into a low score, its verdict
+// alone never approves a comment: the heuristics must agree, or it stays pending.
+func (m *CommentModerator) Moderate(input CommentModerationInput) (string, *ModerationResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), moderationTimeout)
+	defer cancel()
+
+	result, err := m.classifier.Classify(ctx, input)
+	if err != nil {
+		log.Printf("Warning: Comment classifier failed, falling back to heuristics: %v", err)
+		result, _ = HeuristicClassifier{}.Classify(ctx, input)
+	}
+
+	switch {
+	case result.Score >= m.rejectFrom:
+		return "rejected", result
+	case result.Score < m.approveBelow:
+		if result.Source != ModeratedByHeuristic {
+			heuristic, _ := HeuristicClassifier{}.Classify(ctx, input)
+			if heuristic.Score >= m.approveBelow {
+				result.Reasons = append(result.Reasons, heuristic.Reasons...)
+				return "pending", result
+			}
+		}
+		return "approved", result
+	default:
+		return "pending", result
+	}
+}
+
+// AIClassifier scores comments with the configured AI provider
+type AIClassifier struct {
+	aiService *AIService
+}
+
+func NewAIClassifier(aiService *AIService) *AIClassifier {
+	return &AIClassifier{aiService: aiService}
+}
+
+func (c *AIClassifier) Classify(ctx context.Context, input CommentModerationInput) (*ModerationResult, error) {
+	return c.aiService.ClassifyComment(ctx, input)
+}
+
+// Phrases that suggest a comment is spam or abuse. Matched against whole words.
+var (
+	spamPhrases = []string{
+		"buy now", "click here", "free money", "casino", "viagra", "crypto giveaway",
+		"work from home", "limited offer", "seo services", "discount code", "earn money",
+		"make money", "check out my", "visit my", "dm me", "whatsapp",
+	}
+	toxicPhrases = []string{
+		"idiot", "stupid", "moron", "dumb", "shut up", "loser", "garbage", "trash",
+		"hate you", "kill yourself", "pathetic", "retard",
+	}
+)
+
+// HeuristicClassifier scores comments with simple local rules, for when AI is disabled
+type HeuristicClassifier struct{}
+
+func (HeuristicClassifier) Classify(ctx context.Context, input CommentModerationInput) (*ModerationResult, error) {
+	var spam, toxicity, offTopic float64
+	var reasons []string
+
+	text := " " + strings.Join(wordsOf(input.Content), " ") + " "
+	lower := strings.ToLower(input.Content)
+
+	links := strings.Count(lower, "http://") + strings.Count(lower, "https://") + strings.Count(lower, "www.")
+	switch {
+	case links >= 3:
+		spam += 0.8
+	case links == 2:
+		spam += 0.5
+	case links == 1:
+		spam += 0.2
+	}
+	if links > 0 {
+		reasons = append(reasons, fmt.Sprintf("spam: contains %d link(s)", links))
+	}
+
+	for _, phrase := range spamPhrases {
+		if strings.Contains(text, " "+phrase+" ") {
+			spam += 0.3
+			reasons = append(reasons, fmt.Sprintf("spam: contains %q", phrase))
+		}
+	}
+
+	if isShouting(input.Content) {
+		spam += 0.2
+		reasons = append(reasons, "spam: mostly capital letters")
+	}
+
+	for _, phrase := range toxicPhrases {
+		if strings.Contains(text, " "+phrase+" ") {
+			toxicity += 0.4
+			reasons = append(reasons, fmt.Sprintf("toxic: contains %q", phrase))
+		}
+	}
+
+	// Longer comments that share no meaningful words with the post are likely off-topic
+	words := wordsOf(input.Content)
+	if len(words) >= 30 {
+		topic := make(map[string]bool)
+		for _, word := range wordsOf(input.BlogTitle + " " + input.BlogExcerpt + " " + strings.Join(input.BlogTags, " ")) {
+			if len(word) >= 5 {
+				topic[word] = true
+			}
+		}
+
+		related := false
+		for _, word := range words {
+			if topic[word] {
+				related = true
+				break
+			}
+		}
+		if !related && len(topic) > 0 {
+			offTopic = 0.4
+			reasons = append(reasons, "off-topic: shares no key words with the post")
+		}
+	}
+
+	return &ModerationResult{
+		Score:   clampScore(maxScore(spam, toxicity, offTopic)),
+		Reasons: reasons,
+		Source:  ModeratedByHeuristic,
+	}, nil
+}
+
+// wordsOf lowercases text and splits it into words of letters and digits
+func wordsOf(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// isShouting reports whether a comment of some length is written mostly in capitals
+func isShouting(text string) bool {
+	letters, upper := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= 20 && upper*10 > letters*7
+}
+
+func maxScore(scores ...float64) float64 {
+	max := 0.0
+	for _, score := range scores {
+		if score > max {
+			max = score
+		}
+	}
+	return max
+}
+
+func clampScore(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
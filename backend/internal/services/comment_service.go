@@ -5,15 +5,17 @@ import (
 
 	"ai-blog-backend/internal/models"
 
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
 type CommentService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	moderator *CommentModerator // nil leaves every new comment pending
 }
 
-func NewCommentService(db *gorm.DB) *CommentService {
-	return &CommentService{db: db}
+func NewCommentService(db *gorm.DB, moderator *CommentModerator) *CommentService {
+	return &CommentService{db: db, moderator: moderator}
 }
 
 type CreateCommentRequest struct {
@@ -33,16 +35,17 @@ func (s *CommentService) GetComments(blogID string) ([]models.Comment, error) {
 
 func (s *CommentService) AddComment(req CreateCommentRequest) (*models.Comment, error) {
 	// Validate that the blog exists (removed status requirement)
-	var count int64
-	err := s.db.Model(&models.Blog{}).
+	var blogs []models.Blog
+	err := s.db.Select("id, title, excerpt, tags").
 		Where("id = ?", req.BlogID).
-		Count(&count).Error
+		Limit(1).Find(&blogs).Error
 	if err != nil {
 		return nil, fmt.Errorf("error validating blog: %v", err)
 	}
-	if count == 0 {
+	if len(blogs) == 0 {
 		return nil, fmt.Errorf("blog not found")
 	}
+	blog := blogs[0]
 
 	var parentID *string
 	if req.ParentID != "" {
@@ -66,7 +69,22 @@ func (s *CommentService) AddComment(req CreateCommentRequest) (*models.Comment,
 		AuthorEmail: req.AuthorEmail,
 		Content:     req.Content,
 		ParentID:    parentID,
-		Status:      "pending", // All comments start as pending
+		Status:      "pending", // Comments start as pending unless moderation decides otherwise
+	}
+
+	if s.moderator != nil {
+		status, result := s.moderator.Moderate(CommentModerationInput{
+			Content:     req.Content,
+			AuthorName:  req.AuthorName,
+			AuthorEmail: req.AuthorEmail,
+			BlogTitle:   blog.Title,
+			BlogExcerpt: blog.Excerpt,
+			BlogTags:    blog.Tags,
+		})
+		comment.Status = status
+		comment.ModerationScore = &result.Score
+		comment.ModerationReasons = pq.StringArray(result.Reasons)
+		comment.ModeratedBy = result.Source
 	}
 
 	err = s.db.Create(comment).Error
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects how closely
+// the texts are related
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Model() string
+}
+
+// defaultEmbeddingModel is used with the openai provider when no model is configured
+const defaultEmbeddingModel = "text-embedding-ada-002"
+
+// NewEmbedder builds the Embedder described by the config. It accepts the same
+// providers as NewTextGenerator.
+func NewEmbedder(cfg AIConfig) (Embedder, error) {
+	switch cfg.Provider {
+	case "", ProviderOpenAI:
+		model := cfg.Model
+		if model == "" {
+			model = defaultEmbeddingModel
+		}
+		return NewOpenAIEmbedder(cfg.APIKey, cfg.BaseURL, model), nil
+	case ProviderOpenAICompatible:
+		if cfg.BaseURL == "" {
+			return nil, errors.New("openai-compatible provider requires a base URL")
+		}
+		if cfg.Model == "" {
+			return nil, errors.New("openai-compatible provider requires an embedding model")
+		}
+		return NewOpenAIEmbedder(cfg.APIKey, cfg.BaseURL, cfg.Model), nil
+	case ProviderFake:
+		return NewFakeEmbedder(), nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q", cfg.Provider)
+	}
+}
+
+// OpenAIEmbedder uses the OpenAI embeddings API, or any server that implements it.
+// The request is made directly rather than through go-openai, whose embedding
+// requests only accept the model names it knows about.
+type OpenAIEmbedder struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+	model   string
+}
+
+func NewOpenAIEmbedder(apiKey, baseURL, model string) *OpenAIEmbedder {
+	if baseURL == "" {
+		baseURL = openai.DefaultConfig("").BaseURL
+	}
+	return &OpenAIEmbedder{
+		client:  &http.Client{},
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+	}
+}
+
+func (e *OpenAIEmbedder) Model() string {
+	return e.model
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, classifyAIError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, classifyAIError(ctx, embeddingAPIError(resp))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode embeddings: %w", err)
+	}
+
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, data := range result.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	return vectors, nil
+}
+
+// embeddingAPIError reads an error response in the OpenAI format, so it is
+// classified like errors from the chat API
+func embeddingAPIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var errResp openai.ErrorResponse
+	if json.Unmarshal(data, &errResp) == nil && errResp.Error != nil {
+		errResp.Error.HTTPStatusCode = resp.StatusCode
+		return errResp.Error
+	}
+	return &openai.RequestError{
+		HTTPStatusCode: resp.StatusCode,
+		Err:            fmt.Errorf("embeddings request failed: %s", strings.TrimSpace(string(data))),
+	}
+}
+
+// fakeEmbeddingDimensions is the vector size produced by FakeEmbedder
+const fakeEmbeddingDimensions = 256
+
+// FakeEmbedder is a deterministic Embedder for tests and offline development.
+// It hashes words into a fixed number of buckets, so texts sharing words are similar.
+type FakeEmbedder struct{}
+
+func NewFakeEmbedder() *FakeEmbedder {
+	return &FakeEmbedder{}
+}
+
+func (f *FakeEmbedder) Model() string {
+	return "fake"
+}
+
+func (f *FakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, fakeEmbeddingDimensions)
+		for _, word := range wordsOf(text) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			vector[h.Sum32()%fakeEmbeddingDimensions]++
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// normalizeVector scales a vector to unit length, so cosine similarity is a dot product
+func normalizeVector(vector []float32) []float64 {
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	norm = math.Sqrt(norm)
+
+	normalized := make([]float64, len(vector))
+	if norm == 0 {
+		return normalized
+	}
+	for i, v := range vector {
+		normalized[i] = float64(v) / norm
+	}
+	return normalized
+}
+
+// cosineSimilarity compares two unit vectors
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
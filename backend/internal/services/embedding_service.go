@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-blog-backend/internal/models"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// embeddingTimeout bounds a single call to the embedding provider
+const embeddingTimeout = 30 * time.Second
+
+// maxEmbeddingInput keeps embedded text within the provider's input limit, in characters
+const maxEmbeddingInput = 24000
+
+type EmbeddingService struct {
+	db       *gorm.DB
+	embedder Embedder
+}
+
+func NewEmbeddingService(db *gorm.DB, embedder Embedder) *EmbeddingService {
+	return &EmbeddingService{db: db, embedder: embedder}
+}
+
+// IndexBlog stores the embedding of a published blog, or removes it once the blog
+// is no longer published. Blogs whose text hasn't changed are not re-embedded.
+func (s *EmbeddingService) IndexBlog(blog models.Blog) error {
+	if blog.Status != "published" {
+		return s.db.Where("blog_id = ?", blog.ID).Delete(&models.BlogEmbedding{}).Error
+	}
+
+	text := embeddingText(blog)
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(s.embedder.Model()+"\n"+text)))
+
+	var existing []models.BlogEmbedding
+	err := s.db.Select("blog_id, content_hash").Where("blog_id = ?", blog.ID).Limit(1).Find(&existing).Error
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 && existing[0].ContentHash == hash {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), embeddingTimeout)
+	defer cancel()
+
+	vectors, err := s.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return fmt.Errorf("failed to embed blog %s: %w", blog.ID, err)
+	}
+
+	embedding := models.BlogEmbedding{
+		BlogID:      blog.ID,
+		Model:       s.embedder.Model(),
+		Embedding:   pq.Float64Array(normalizeVector(vectors[0])),
+		ContentHash: hash,
+	}
+	return s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&embedding).Error
+}
+
+// OnBlogSaved keeps embeddings up to date; register it with BlogService.AddSaveListener
+func (s *EmbeddingService) OnBlogSaved(blog models.Blog) {
+	if err := s.IndexBlog(blog); err != nil {
+		log.Printf("Warning: Could not update embedding for blog %s: %v", blog.ID, err)
+	}
+}
+
+// IndexMissing embeds published blogs that have no embedding from the current model yet
+func (s *EmbeddingService) IndexMissing() error {
+	var blogs []models.Blog
+	err := s.db.Where("status = ? AND NOT EXISTS (SELECT 1 FROM blog_embeddings WHERE blog_embeddings.blog_id = blogs.id AND blog_embeddings.model = ?)",
+		"published", s.embedder.Model()).Find(&blogs).Error
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, blog := range blogs {
+		if err := s.IndexBlog(blog); err != nil {
+			log.Printf("Warning: Could not embed blog %s: %v", blog.ID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d blogs could not be embedded", failed, len(blogs))
+	}
+	return nil
+}
+
+// RelatedBlogs returns up to limit published blogs in the same language that are
+// most similar to the given one, excluding its own translations
+func (s *EmbeddingService) RelatedBlogs(blogID string, limit int) ([]models.RelatedBlog, error) {
+	var target models.Blog
+	err := s.db.Where("id = ? AND status = ?", blogID, "published").First(&target).Error
+	if err != nil {
+		return nil, err
+	}
+
+	embedding, err := s.getEmbedding(target)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.Model(&models.BlogEmbedding{}).
+		Joins("JOIN blogs ON blogs.id = blog_embeddings.blog_id").
+		Where("blog_embeddings.model = ? AND blog_embeddings.blog_id <> ?", embedding.Model, target.ID).
+		Where("blogs.status = ? AND blogs.locale = ? AND blogs.deleted_at IS NULL", "published", target.Locale)
+	if target.TranslationGroupID != nil {
+		query = query.Where("(blogs.translation_group_id IS NULL OR blogs.translation_group_id <> ?)", *target.TranslationGroupID)
+	}
+
+	var candidates []models.BlogEmbedding
+	if err := query.Select("blog_embeddings.blog_id, blog_embeddings.embedding").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64, len(candidates))
+	for _, candidate := range candidates {
+		scores[candidate.BlogID] = cosineSimilarity(embedding.Embedding, candidate.Embedding)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return scores[candidates[i].BlogID] > scores[candidates[j].BlogID]
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	ids := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.BlogID
+	}
+
+	var blogs []models.Blog
+	if len(ids) > 0 {
+		if err := s.db.Where("id IN ?", ids).Find(&blogs).Error; err != nil {
+			return nil, err
+		}
+	}
+	byID := make(map[string]models.Blog, len(blogs))
+	for _, blog := range blogs {
+		byID[blog.ID] = blog
+	}
+
+	related := make([]models.RelatedBlog, 0, len(ids))
+	for _, id := range ids {
+		if blog, ok := byID[id]; ok {
+			related = append(related, models.RelatedBlog{Blog: blog, Similarity: scores[id]})
+		}
+	}
+	return related, nil
+}
+
+// getEmbedding returns the blog's stored embedding, computing it first if needed
+func (s *EmbeddingService) getEmbedding(blog models.Blog) (*models.BlogEmbedding, error) {
+	var embedding models.BlogEmbedding
+	err := s.db.Where("blog_id = ? AND model = ?", blog.ID, s.embedder.Model()).First(&embedding).Error
+	if err == nil {
+		return &embedding, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := s.IndexBlog(blog); err != nil {
+		return nil, err
+	}
+	err = s.db.Where("blog_id = ?", blog.ID).First(&embedding).Error
+	if err != nil {
+		return nil, err
+	}
+	return &embedding, nil
+}
+
+// embeddingText is the text of a blog that its embedding represents
+func embeddingText(blog models.Blog) string {
+	text := strings.Join([]string{blog.Title, strings.Join(blog.Tags, ", "), blog.Excerpt, blog.Content}, "\n\n")
+	return firstRunes(text, maxEmbeddingInput)
+}
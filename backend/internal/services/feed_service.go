@@ -0,0 +1,369 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"ai-blog-backend/internal/models"
+
+	"github.com/yuin/goldmark"
+	"gorm.io/gorm"
+)
+
+// feedItemLimit caps the number of posts included in a single feed
+const feedItemLimit = 50
+
+// SiteConfig describes the public site that feeds and sitemaps link to
+type SiteConfig struct {
+	URL         string // Public frontend URL, without a trailing slash
+	Title       string
+	Description string
+}
+
+// BlogURL returns the public URL of a blog post
+func (c SiteConfig) BlogURL(slug string) string {
+	return c.URL + "/blog/" + slug
+}
+
+// AuthorURL returns the public URL of an author's page
+func (c SiteConfig) AuthorURL(authorID string) string {
+	return c.URL + "/authors/" + url.PathEscape(authorID)
+}
+
+// TagURL returns the public URL of a tag's page
+func (c SiteConfig) TagURL(tag string) string {
+	return c.URL + "/tags/" + url.PathEscape(tag)
+}
+
+type FeedService struct {
+	db   *gorm.DB
+	site SiteConfig
+}
+
+func NewFeedService(db *gorm.DB, site SiteConfig) *FeedService {
+	site.URL = strings.TrimRight(site.URL, "/")
+	return &FeedService{db: db, site: site}
+}
+
+type FeedOptions struct {
+	AuthorID    string // Only include posts by this author
+	Tag         string // Only include posts with this tag
+	FullContent bool   // Include the full post instead of the excerpt
+	SelfURL     string // URL the feed is being served from
+}
+
+// Feed is a list of published blogs ready to be rendered in any feed format
+type Feed struct {
+	Title       string
+	Description string
+	Link        string
+	Updated     time.Time // Latest UpdatedAt of the included blogs
+	Options     FeedOptions
+	Blogs       []models.Blog
+}
+
+// GetFeed loads the most recently published blogs matching the options
+func (s *FeedService) GetFeed(opts FeedOptions) (*Feed, error) {
+	query := s.db.Where("status = ?", "published")
+	if opts.AuthorID != "" {
+		query = query.Where("author_id = ?", opts.AuthorID)
+	}
+	if opts.Tag != "" {
+		query = query.Where("? = ANY(tags)", opts.Tag)
+	}
+
+	var blogs []models.Blog
+	err := query.Order("published_at DESC").Limit(feedItemLimit).Find(&blogs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{
+		Title:       s.site.Title,
+		Description: s.site.Description,
+		Link:        s.site.URL,
+		Options:     opts,
+		Blogs:       blogs,
+	}
+
+	switch {
+	case opts.AuthorID != "":
+		authorName := opts.AuthorID
+		if len(blogs) > 0 && blogs[0].AuthorName != "" {
+			authorName = blogs[0].AuthorName
+		}
+		feed.Title = fmt.Sprintf("%s - Posts by %s", s.site.Title, authorName)
+		feed.Link = s.site.AuthorURL(opts.AuthorID)
+	case opts.Tag != "":
+		feed.Title = fmt.Sprintf("%s - Posts tagged %s", s.site.Title, opts.Tag)
+		feed.Link = s.site.TagURL(opts.Tag)
+	}
+
+	for _, blog := range blogs {
+		if blog.UpdatedAt.After(feed.Updated) {
+			feed.Updated = blog.UpdatedAt
+		}
+	}
+
+	return feed, nil
+}
+
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	DCNS      string     `xml:"xmlns:dc,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	AtomLink      rssAtomLink `xml:"atom:link"`
+	LastBuildDate string      `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem   `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"content:encoded,omitempty"`
+}
+
+// RenderRSS renders the feed as RSS 2.0
+func (s *FeedService) RenderRSS(feed *Feed) ([]byte, error) {
+	doc := rssFeed{
+		Version:   "2.0",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		DCNS:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       feed.Title,
+			Link:        feed.Link,
+			Description: feed.Description,
+			AtomLink:    rssAtomLink{Href: feed.Options.SelfURL, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	if !feed.Updated.IsZero() {
+		doc.Channel.LastBuildDate = feed.Updated.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, blog := range feed.Blogs {
+		item := rssItem{
+			Title:       blog.Title,
+			Link:        s.site.BlogURL(blog.Slug),
+			GUID:        rssGUID{Value: "urn:uuid:" + blog.ID},
+			Creator:     blog.AuthorName,
+			Categories:  blog.Tags,
+			Description: blog.Excerpt,
+		}
+		if blog.PublishedAt != nil {
+			item.PubDate = blog.PublishedAt.UTC().Format(time.RFC1123Z)
+		}
+		if feed.Options.FullContent {
+			html, err := renderMarkdown(blog.Content)
+			if err != nil {
+				return nil, err
+			}
+			item.Content = html
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+
+	return marshalXML(doc)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Author     atomPerson     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+// RenderAtom renders the feed as Atom 1.0
+func (s *FeedService) RenderAtom(feed *Feed) ([]byte, error) {
+	updated := feed.Updated
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+
+	doc := atomFeed{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		Title:   feed.Title,
+		ID:      feed.Options.SelfURL,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.Link, Rel: "alternate", Type: "text/html"},
+			{Href: feed.Options.SelfURL, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+
+	for _, blog := range feed.Blogs {
+		entry := atomEntry{
+			Title:   blog.Title,
+			ID:      "urn:uuid:" + blog.ID,
+			Updated: blog.UpdatedAt.UTC().Format(time.RFC3339),
+			Links:   []atomLink{{Href: s.site.BlogURL(blog.Slug), Rel: "alternate", Type: "text/html"}},
+			Author:  atomPerson{Name: blog.AuthorName},
+			Summary: &atomText{Type: "text", Body: blog.Excerpt},
+		}
+		if blog.PublishedAt != nil {
+			entry.Published = blog.PublishedAt.UTC().Format(time.RFC3339)
+		}
+		for _, tag := range blog.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		if feed.Options.FullContent {
+			html, err := renderMarkdown(blog.Content)
+			if err != nil {
+				return nil, err
+			}
+			entry.Content = &atomText{Type: "html", Body: html}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return marshalXML(doc)
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html,omitempty"`
+	ContentText   string           `json:"content_text,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+// RenderJSON renders the feed as JSON Feed 1.1
+func (s *FeedService) RenderJSON(feed *Feed) ([]byte, error) {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: feed.Link,
+		FeedURL:     feed.Options.SelfURL,
+		Description: feed.Description,
+		Items:       []jsonFeedItem{},
+	}
+
+	for _, blog := range feed.Blogs {
+		item := jsonFeedItem{
+			ID:           blog.ID,
+			URL:          s.site.BlogURL(blog.Slug),
+			Title:        blog.Title,
+			Summary:      blog.Excerpt,
+			Image:        blog.FeaturedImage,
+			DateModified: blog.UpdatedAt.UTC().Format(time.RFC3339),
+			Authors:      []jsonFeedAuthor{{Name: blog.AuthorName}},
+			Tags:         blog.Tags,
+		}
+		if blog.PublishedAt != nil {
+			item.DatePublished = blog.PublishedAt.UTC().Format(time.RFC3339)
+		}
+		if feed.Options.FullContent {
+			html, err := renderMarkdown(blog.Content)
+			if err != nil {
+				return nil, err
+			}
+			item.ContentHTML = html
+		} else {
+			// JSON Feed items need content, so fall back to the excerpt
+			item.ContentText = blog.Excerpt
+		}
+		doc.Items = append(doc.Items, item)
+	}
+
+	return json.Marshal(doc)
+}
+
+func marshalXML(doc interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// renderMarkdown converts markdown blog content to HTML
+func renderMarkdown(content string) (string, error) {
+	var buf bytes.Buffer
+	if err := goldmark.Convert([]byte(content), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// markdownBlock is the plain text of a heading or paragraph of markdown content
+type markdownBlock struct {
+	Text    string
+	Heading bool
+}
+
+// markdownBlocks returns the plain text of the headings and paragraphs of markdown
+// content, in order. Code blocks, raw HTML and images are left out.
+func markdownBlocks(content string) []markdownBlock {
+	source := []byte(content)
+	doc := goldmark.DefaultParser().Parse(text.NewReader(source))
+
+	var blocks []markdownBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindHeading, ast.KindParagraph, ast.KindTextBlock:
+			var b strings.Builder
+			inlineText(&b, n, source)
+			if t := strings.Join(strings.Fields(b.String()), " "); t != "" {
+				blocks = append(blocks, markdownBlock{Text: t, Heading: n.Kind() == ast.KindHeading})
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return blocks
+}
+
+// inlineText writes the text of an inline node and its children, skipping images and HTML
+func inlineText(b *strings.Builder, n ast.Node, source []byte) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch node := c.(type) {
+		case *ast.Image, *ast.RawHTML:
+			continue
+		case *ast.Text:
+			b.Write(node.Segment.Value(source))
+			if node.SoftLineBreak() || node.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(node.Value)
+		case *ast.AutoLink:
+			b.Write(node.URL(source))
+		default:
+			inlineText(b, c, source)
+		}
+	}
+}
+
+// plainText returns markdown content as plain text, one block per line
+func plainText(content string) string {
+	var lines []string
+	for _, block := range markdownBlocks(content) {
+		lines = append(lines, block.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateText shortens text to at most limit characters, breaking between words
+// and adding an ellipsis when anything was cut
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	cut := limit
+	for i := limit; i > limit/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "..."
+}
+
+// splitSentences splits plain text into sentences at ., ! and ? followed by a space
+func splitSentences(text string) []string {
+	var sentences []string
+	runes := []rune(text)
+	start := 0
+	for i, r := range runes {
+		end := i == len(runes)-1
+		if !end && (r == '.' || r == '!' || r == '?') && unicode.IsSpace(runes[i+1]) {
+			end = true
+		}
+		if end {
+			if sentence := strings.TrimSpace(string(runes[start : i+1])); sentence != "" {
+				sentences = append(sentences, sentence)
+			}
+			start = i + 1
+		}
+	}
+	return sentences
+}
+
+// markdownHeading is a heading of markdown content
+type markdownHeading struct {
+	Level int
+	Text  string
+}
+
+// markdownImage is an image embedded in markdown content
+type markdownImage struct {
+	Alt         string
+	Destination string
+}
+
+// markdownElements lists the headings, images and links of markdown content
+type markdownElements struct {
+	Headings []markdownHeading
+	Images   []markdownImage
+	Links    []string // Link destinations
+}
+
+// inspectMarkdown collects the headings, images and links of markdown content, in order
+func inspectMarkdown(content string) markdownElements {
+	source := []byte(content)
+	doc := goldmark.DefaultParser().Parse(text.NewReader(source))
+
+	var elements markdownElements
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			var b strings.Builder
+			inlineText(&b, node, source)
+			elements.Headings = append(elements.Headings, markdownHeading{Level: node.Level, Text: strings.TrimSpace(b.String())})
+		case *ast.Image:
+			var b strings.Builder
+			inlineText(&b, node, source)
+			elements.Images = append(elements.Images, markdownImage{Alt: strings.TrimSpace(b.String()), Destination: string(node.Destination)})
+			return ast.WalkSkipChildren, nil
+		case *ast.Link:
+			elements.Links = append(elements.Links, string(node.Destination))
+		case *ast.AutoLink:
+			elements.Links = append(elements.Links, string(node.URL(source)))
+		}
+		return ast.WalkContinue, nil
+	})
+	return elements
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"ai-blog-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Names of the prompts that can be managed as templates
+const (
+	PromptGenerateContent = "generate_content"
+	PromptGenerateMeta    = "generate_meta"
+	PromptGenerateTags    = "generate_tags"
+)
+
+// promptCacheTTL is how long active template versions are cached between database reads
+const promptCacheTTL = 30 * time.Second
+
+var (
+	ErrUnknownPrompt = errors.New("unknown prompt")
+	// ErrInvalidPromptTemplate wraps template parse and execution errors
+	ErrInvalidPromptTemplate = errors.New("invalid prompt template")
+)
+
+// builtinPrompt is the default template of a prompt, used while no version of it
+// is active in the database. Sample holds the prompt's variables with example values.
+type builtinPrompt struct {
+	Body   string
+	Sample map[string]interface{}
+}
+
+var builtinPrompts = map[string]builtinPrompt{
+	PromptGenerateContent: {
+		Body: `Write a comprehensive blog post with the following details:
+
+Title: {{.Title}}
+Description: {{.Description}}
+Tone: {{.Tone}}
+Length: {{.WordCount}}
+
+Please write a well-structured blog post that:
+1. Has an engaging introduction
+2. Is organized with clear headings and subheadings
+3. Includes practical examples or insights
+4. Has a strong conclusion
+5. Uses a {{.Tone}} tone throughout
+6. Is approximately {{.WordCount}} in length
+
+Format the response as a complete blog post in markdown format.`,
+		Sample: map[string]interface{}{
+			"Title":       "Getting started with Go",
+			"Description": "A beginner's guide to the Go programming language",
+			"Tone":        ToneProfessional,
+			"WordCount":   "800-1500 words",
+		},
+	},
+	PromptGenerateMeta: {
+		Body: `Based on the following blog post title and content, generate SEO-optimized meta information:
+
+Title: {{.Title}}
+Content: {{.Content}}
+
+Please provide:
+1. A compelling meta title ({{.MetaTitleMin}}-{{.MetaTitleMax}} characters)
+2. A descriptive meta description ({{.MetaDescriptionMin}}-{{.MetaDescriptionMax}} characters)
+3. {{.MinTags}}-{{.MaxTags}} relevant tags/keywords
+
+Respond with a JSON object in exactly this shape:
+{"metaTitle": "...", "metaDescription": "...", "tags": ["tag1", "tag2", "..."]}`,
+		Sample: map[string]interface{}{
+			"Title":              "Getting started with Go",
+			"Content":            "Go is a statically typed, compiled language...",
+			"MetaTitleMin":       metaTitleMinLength,
+			"MetaTitleMax":       metaTitleMaxLength,
+			"MetaDescriptionMin": metaDescriptionMinLength,
+			"MetaDescriptionMax": metaDescriptionMaxLength,
+			"MinTags":            minTags,
+			"MaxTags":            maxTags,
+		},
+	},
+	PromptGenerateTags: {
+		Body: `Based on the following blog post title and content, suggest {{.MinTags}}-{{.MaxTags}} relevant tags/keywords:
+
+Title: {{.Title}}
+Content: {{.Content}}
+
+Respond with a JSON object in exactly this shape:
+{"tags": ["tag1", "tag2", "..."]}`,
+		Sample: map[string]interface{}{
+			"Title":   "Getting started with Go",
+			"Content": "Go is a statically typed, compiled language...",
+			"MinTags": minTags,
+			"MaxTags": maxTags,
+		},
+	},
+}
+
+// builtinTemplates are the parsed built-in prompts
+var builtinTemplates = func() map[string]*template.Template {
+	templates := make(map[string]*template.Template, len(builtinPrompts))
+	for name, builtin := range builtinPrompts {
+		templates[name] = template.Must(parsePrompt(name, builtin.Body))
+	}
+	return templates
+}()
+
+// Prompt is the template version chosen for one AI request
+type Prompt struct {
+	Version string // e.g. generate_meta@v3, or generate_meta@builtin
+	tmpl    *template.Template
+}
+
+// Render fills in the prompt's variables
+func (p *Prompt) Render(vars map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", p.Version, err)
+	}
+	return buf.String(), nil
+}
+
+type CreatePromptVersionRequest struct {
+	Body   string `json:"body" binding:"required"`
+	Weight int    `json:"weight" binding:"min=0"` // 0 saves the version without using it
+	Notes  string `json:"notes"`
+}
+
+type UpdatePromptVersionRequest struct {
+	Weight *int `json:"weight" binding:"required,min=0"`
+}
+
+// PromptInfo describes a prompt and all its stored versions
+type PromptInfo struct {
+	Name      string                  `json:"name"`
+	Variables []string                `json:"variables"`
+	Builtin   string                  `json:"builtin"` // Used while no version is active
+	Versions  []models.PromptTemplate `json:"versions"`
+}
+
+type cachedPrompt struct {
+	version string
+	weight  int
+	tmpl    *template.Template
+}
+
+// PromptService stores versions of the AI prompts and picks one for each request.
+// When several versions are active they are chosen at random in proportion to
+// their weights, so prompts can be A/B tested.
+type PromptService struct {
+	db *gorm.DB
+
+	mu       sync.Mutex
+	active   map[string][]cachedPrompt
+	loadedAt time.Time
+}
+
+func NewPromptService(db *gorm.DB) *PromptService {
+	return &PromptService{db: db}
+}
+
+// Choose picks the template version to use for a prompt. A nil PromptService
+// always uses the built-in templates.
+func (s *PromptService) Choose(name string) (*Prompt, error) {
+	if _, ok := builtinPrompts[name]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownPrompt, name)
+	}
+
+	if s != nil {
+		active, err := s.activeVersions(name)
+		if err != nil {
+			return nil, err
+		}
+		if prompt := pickWeighted(active); prompt != nil {
+			return prompt, nil
+		}
+	}
+
+	return &Prompt{Version: name + "@builtin", tmpl: builtinTemplates[name]}, nil
+}
+
+// ListPrompts describes every prompt with its versions, newest first
+func (s *PromptService) ListPrompts() ([]PromptInfo, error) {
+	names := make([]string, 0, len(builtinPrompts))
+	for name := range builtinPrompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	prompts := make([]PromptInfo, 0, len(names))
+	for _, name := range names {
+		info, err := s.GetPrompt(name)
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, *info)
+	}
+	return prompts, nil
+}
+
+// GetPrompt describes one prompt with its versions, newest first
+func (s *PromptService) GetPrompt(name string) (*PromptInfo, error) {
+	builtin, ok := builtinPrompts[name]
+	if !ok {
+		return nil, ErrUnknownPrompt
+	}
+
+	versions := []models.PromptTemplate{}
+	if err := s.db.Where("name = ?", name).Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+
+	variables := make([]string, 0, len(builtin.Sample))
+	for variable := range builtin.Sample {
+		variables = append(variables, variable)
+	}
+	sort.Strings(variables)
+
+	return &PromptInfo{Name: name, Variables: variables, Builtin: builtin.Body, Versions: versions}, nil
+}
+
+// CreateVersion stores a new version of a prompt after checking that it renders
+// with the prompt's variables
+func (s *PromptService) CreateVersion(name string, req CreatePromptVersionRequest, createdBy string) (*models.PromptTemplate, error) {
+	builtin, ok := builtinPrompts[name]
+	if !ok {
+		return nil, ErrUnknownPrompt
+	}
+
+	tmpl, err := parsePrompt(name, req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPromptTemplate, err)
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, builtin.Sample); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPromptTemplate, err)
+	}
+
+	prompt := models.PromptTemplate{
+		Name:      name,
+		Body:      req.Body,
+		Weight:    req.Weight,
+		Notes:     req.Notes,
+		CreatedBy: createdBy,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Serialize version numbering per prompt
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "prompt_templates:"+name).Error; err != nil {
+			return err
+		}
+		var latest int
+		err := tx.Model(&models.PromptTemplate{}).
+			Select("COALESCE(MAX(version), 0)").
+			Where("name = ?", name).
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+		prompt.Version = latest + 1
+		return tx.Create(&prompt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate()
+	return &prompt, nil
+}
+
+// SetWeight changes how often a version is used; 0 turns it off
+func (s *PromptService) SetWeight(name string, version, weight int) (*models.PromptTemplate, error) {
+	result := s.db.Model(&models.PromptTemplate{}).
+		Where("name = ? AND version = ?", name, version).
+		Update("weight", weight)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	s.invalidate()
+
+	var prompt models.PromptTemplate
+	if err := s.db.Where("name = ? AND version = ?", name, version).First(&prompt).Error; err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// activeVersions returns the versions of a prompt with a positive weight,
+// reloading them from the database once the cache is stale
+func (s *PromptService) activeVersions(name string) ([]cachedPrompt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil || time.Since(s.loadedAt) > promptCacheTTL {
+		var templates []models.PromptTemplate
+		if err := s.db.Where("weight > 0").Find(&templates).Error; err != nil {
+			return nil, err
+		}
+
+		active := make(map[string][]cachedPrompt)
+		for _, t := range templates {
+			tmpl, err := parsePrompt(t.Name, t.Body)
+			if err != nil {
+				// Versions are checked when saved, so this only happens after a template syntax change
+				continue
+			}
+			active[t.Name] = append(active[t.Name], cachedPrompt{
+				version: fmt.Sprintf("%s@v%d", t.Name, t.Version),
+				weight:  t.Weight,
+				tmpl:    tmpl,
+			})
+		}
+		s.active = active
+		s.loadedAt = time.Now()
+	}
+
+	return s.active[name], nil
+}
+
+func (s *PromptService) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = nil
+}
+
+// pickWeighted chooses one of the versions in proportion to its weight
+func pickWeighted(versions []cachedPrompt) *Prompt {
+	total := 0
+	for _, v := range versions {
+		total += v.weight
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	n := rand.Intn(total)
+	for _, v := range versions {
+		if n < v.weight {
+			return &Prompt{Version: v.version, tmpl: v.tmpl}
+		}
+		n -= v.weight
+	}
+	return nil
+}
+
+// parsePrompt parses a prompt template. Referencing a variable the prompt doesn't
+// have is an error rather than an empty string.
+func parsePrompt(name, body string) (*template.Template, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, errors.New("template is empty")
+	}
+	return template.New(name).Option("missingkey=error").Parse(body)
+}
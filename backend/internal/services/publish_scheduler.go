@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// PublishScheduler periodically publishes scheduled blogs once their publish time has passed
+type PublishScheduler struct {
+	blogService *BlogService
+	interval    time.Duration
+}
+
+func NewPublishScheduler(blogService *BlogService, interval time.Duration) *PublishScheduler {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &PublishScheduler{blogService: blogService, interval: interval}
+}
+
+// Start runs the scheduler in the background until the context is cancelled
+func (p *PublishScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		p.publishDue()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.publishDue()
+			}
+		}
+	}()
+}
+
+func (p *PublishScheduler) publishDue() {
+	ids, err := p.blogService.PublishDueBlogs()
+	if err != nil {
+		log.Printf("Warning: Could not publish scheduled blogs: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		log.Printf("Published scheduled blog %s", id)
+	}
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-blog-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Response cache backends supported by AI_CACHE
+const (
+	CacheMemory   = "memory"
+	CacheDatabase = "db"
+	CacheOff      = "off"
+)
+
+// ResponseCache stores AI responses by cache key. Implementations must be safe
+// for concurrent use.
+type ResponseCache interface {
+	// Get returns the cached result for key, or nil if there is none or it has expired
+	Get(ctx context.Context, key string) (*TextGenerationResult, error)
+	Set(ctx context.Context, key string, result *TextGenerationResult, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type cacheBypassKey struct{}
+
+// bypassCache returns a context whose model calls skip cached responses when force
+// is set. Fresh responses still replace the cached ones.
+func bypassCache(ctx context.Context, force bool) context.Context {
+	if !force {
+		return ctx
+	}
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// CachingGenerator is a TextGenerator that answers repeated requests from a cache
+// instead of calling the provider again. Requests are keyed by model, prompt
+// template versions and the full prompt.
+type CachingGenerator struct {
+	next  TextGenerator
+	cache ResponseCache
+	ttl   time.Duration
+}
+
+func NewCachingGenerator(next TextGenerator, cache ResponseCache, ttl time.Duration) *CachingGenerator {
+	return &CachingGenerator{next: next, cache: cache, ttl: ttl}
+}
+
+func (g *CachingGenerator) Model() string {
+	return g.next.Model()
+}
+
+func (g *CachingGenerator) Generate(ctx context.Context, req TextGenerationRequest) (*TextGenerationResult, error) {
+	key := g.key(ctx, req)
+	if cached := g.lookup(ctx, key); cached != nil {
+		return cached, nil
+	}
+
+	result, err := g.next.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	g.store(ctx, key, result)
+	return result, nil
+}
+
+// Stream replays a cached response as a single chunk
+func (g *CachingGenerator) Stream(ctx context.Context, req TextGenerationRequest, onDelta func(delta string) error) (*TextGenerationResult, error) {
+	key := g.key(ctx, req)
+	if cached := g.lookup(ctx, key); cached != nil {
+		// The cached result is returned even on error so it isn't counted as paid
+		return cached, onDelta(cached.Content)
+	}
+
+	result, err := g.next.Stream(ctx, req, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	g.store(ctx, key, result)
+	return result, nil
+}
+
+// Forget drops the cached response to a request, e.g. because it failed validation
+func (g *CachingGenerator) Forget(ctx context.Context, req TextGenerationRequest) {
+	if err := g.cache.Delete(ctx, g.key(ctx, req)); err != nil {
+		log.Printf("Warning: Could not delete cached AI response: %v", err)
+	}
+}
+
+// key hashes everything that determines a response
+func (g *CachingGenerator) key(ctx context.Context, req TextGenerationRequest) string {
+	payload, _ := json.Marshal(struct {
+		Model   string
+		Prompts []string
+		Request TextGenerationRequest
+	}{
+		Model:   g.next.Model(),
+		Prompts: promptVersions(ctx),
+		Request: req,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup returns a copy of the cached response marked as cached, or nil on a miss.
+// Cache errors are logged and treated as misses.
+func (g *CachingGenerator) lookup(ctx context.Context, key string) *TextGenerationResult {
+	if bypass, _ := ctx.Value(cacheBypassKey{}).(bool); bypass {
+		return nil
+	}
+
+	cached, err := g.cache.Get(ctx, key)
+	if err != nil {
+		log.Printf("Warning: Could not read cached AI response: %v", err)
+		return nil
+	}
+	if cached == nil {
+		return nil
+	}
+
+	hit := *cached
+	hit.Cached = true
+	return &hit
+}
+
+func (g *CachingGenerator) store(ctx context.Context, key string, result *TextGenerationResult) {
+	if strings.TrimSpace(result.Content) == "" {
+		return
+	}
+	if err := g.cache.Set(ctx, key, result, g.ttl); err != nil {
+		log.Printf("Warning: Could not cache AI response: %v", err)
+	}
+}
+
+// forgetResponse drops a cached response from the generator's cache, if it has one
+func forgetResponse(ctx context.Context, generator TextGenerator, req TextGenerationRequest) {
+	if cache, ok := generator.(*CachingGenerator); ok {
+		cache.Forget(ctx, req)
+	}
+}
+
+// MemoryCache is an in-process ResponseCache that evicts the least recently used
+// response once it holds maxEntries
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // Most recently used at the front
+}
+
+type memoryCacheEntry struct {
+	key       string
+	result    TextGenerationResult
+	expiresAt time.Time
+}
+
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (*TextGenerationResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(element)
+		return nil, nil
+	}
+
+	c.order.MoveToFront(element)
+	result := entry.result
+	return &result, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, result *TextGenerationResult, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memoryCacheEntry{key: key, result: *result, expiresAt: time.Now().Add(ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	return nil
+}
+
+func (c *MemoryCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*memoryCacheEntry).key)
+}
+
+// dbCachePruneInterval is how often expired responses are deleted from the database
+const dbCachePruneInterval = time.Hour
+
+// DBCache is a ResponseCache stored in the database, so responses survive
+// restarts and are shared between instances
+type DBCache struct {
+	db *gorm.DB
+
+	mu       sync.Mutex
+	prunedAt time.Time
+}
+
+func NewDBCache(db *gorm.DB) *DBCache {
+	return &DBCache{db: db}
+}
+
+func (c *DBCache) Get(ctx context.Context, key string) (*TextGenerationResult, error) {
+	var entry models.AIResponseCache
+	err := c.db.WithContext(ctx).Where("key = ? AND expires_at > ?", key, time.Now()).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TextGenerationResult{
+		Content:          entry.Content,
+		Model:            entry.Model,
+		PromptTokens:     entry.PromptTokens,
+		CompletionTokens: entry.CompletionTokens,
+	}, nil
+}
+
+func (c *DBCache) Set(ctx context.Context, key string, result *TextGenerationResult, ttl time.Duration) error {
+	c.prune(ctx)
+
+	entry := models.AIResponseCache{
+		Key:              key,
+		Model:            result.Model,
+		Content:          result.Content,
+		PromptTokens:     result.PromptTokens,
+		CompletionTokens: result.CompletionTokens,
+		ExpiresAt:        time.Now().Add(ttl),
+	}
+	return c.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&entry).Error
+}
+
+func (c *DBCache) Delete(ctx context.Context, key string) error {
+	return c.db.WithContext(ctx).Where("key = ?", key).Delete(&models.AIResponseCache{}).Error
+}
+
+// prune deletes expired responses, at most once per dbCachePruneInterval
+func (c *DBCache) prune(ctx context.Context) {
+	c.mu.Lock()
+	if time.Since(c.prunedAt) < dbCachePruneInterval {
+		c.mu.Unlock()
+		return
+	}
+	c.prunedAt = time.Now()
+	c.mu.Unlock()
+
+	if err := c.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.AIResponseCache{}).Error; err != nil {
+		log.Printf("Warning: Could not prune cached AI responses: %v", err)
+	}
+}
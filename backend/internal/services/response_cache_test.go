@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries int
+		steps      []string        // "set k", "set-expired k", "get k" or "delete k"
+		want       map[string]bool // Whether Get finds each key afterwards
+	}{
+		{
+			name:       "set",
+			maxEntries: 2,
+			steps:      []string{"set a"},
+			want:       map[string]bool{"a": true, "b": false},
+		},
+		{
+			name:       "evicts the least recently used",
+			maxEntries: 2,
+			steps:      []string{"set a", "set b", "set c"},
+			want:       map[string]bool{"a": false, "b": true, "c": true},
+		},
+		{
+			name:       "get counts as use",
+			maxEntries: 2,
+			steps:      []string{"set a", "set b", "get a", "set c"},
+			want:       map[string]bool{"a": true, "b": false, "c": true},
+		},
+		{
+			name:       "set of an existing key counts as use",
+			maxEntries: 2,
+			steps:      []string{"set a", "set b", "set a", "set c"},
+			want:       map[string]bool{"a": true, "b": false, "c": true},
+		},
+		{
+			name:       "misses don't count as use",
+			maxEntries: 2,
+			steps:      []string{"set a", "set b", "get x", "set c"},
+			want:       map[string]bool{"a": false, "b": true, "c": true},
+		},
+		{
+			name:       "delete",
+			maxEntries: 2,
+			steps:      []string{"set a", "set b", "delete a", "set c", "delete x"},
+			want:       map[string]bool{"a": false, "b": true, "c": true},
+		},
+		{
+			name:       "expired",
+			maxEntries: 2,
+			steps:      []string{"set-expired a", "set b"},
+			want:       map[string]bool{"a": false, "b": true},
+		},
+		{
+			name:       "set replaces an expired response",
+			maxEntries: 2,
+			steps:      []string{"set-expired a", "set a"},
+			want:       map[string]bool{"a": true},
+		},
+		{
+			name:       "unbounded",
+			maxEntries: 0,
+			steps:      []string{"set a", "set b", "set c"},
+			want:       map[string]bool{"a": true, "b": true, "c": true},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewMemoryCache(tt.maxEntries)
+			for _, step := range tt.steps {
+				op, key, _ := strings.Cut(step, " ")
+				var err error
+				switch op {
+				case "set":
+					err = cache.Set(ctx, key, &TextGenerationResult{Content: "response " + key}, time.Hour)
+				case "set-expired":
+					err = cache.Set(ctx, key, &TextGenerationResult{Content: "response " + key}, -time.Second)
+				case "get":
+					_, err = cache.Get(ctx, key)
+				case "delete":
+					err = cache.Delete(ctx, key)
+				}
+				if err != nil {
+					t.Fatalf("%s: %v", step, err)
+				}
+			}
+
+			for key, want := range tt.want {
+				result, _ := cache.Get(ctx, key)
+				if found := result != nil; found != want {
+					t.Errorf("Get(%q) found = %v, want %v", key, found, want)
+				} else if found && result.Content != "response "+key {
+					t.Errorf("Get(%q) = %q", key, result.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(10)
+	cache.Set(ctx, "a", &TextGenerationResult{Content: "response"}, 20*time.Millisecond)
+
+	if result, _ := cache.Get(ctx, "a"); result == nil {
+		t.Fatal("response expired before its TTL")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if result, _ := cache.Get(ctx, "a"); result != nil {
+		t.Errorf("Get() after the TTL = %+v", result)
+	}
+	if len(cache.entries) != 0 || cache.order.Len() != 0 {
+		t.Errorf("expired response still held")
+	}
+}
+
+func TestMemoryCacheCopiesResults(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(10)
+
+	result := &TextGenerationResult{Content: "original"}
+	cache.Set(ctx, "a", result, time.Hour)
+	result.Content = "changed by the caller"
+	if got, _ := cache.Get(ctx, "a"); got.Content != "original" {
+		t.Errorf("Get() = %q after the stored result changed", got.Content)
+	}
+
+	got, _ := cache.Get(ctx, "a")
+	got.Content = "changed by a reader"
+	if again, _ := cache.Get(ctx, "a"); again.Content != "original" {
+		t.Errorf("Get() = %q after a returned result changed", again.Content)
+	}
+}
+
+func TestCachingGenerator(t *testing.T) {
+	tests := []struct {
+		name       string
+		responses  []string
+		force      bool // The second request bypasses the cache
+		wantCalls  int
+		wantCached bool // The second result came from the cache
+	}{
+		{name: "repeated request", responses: []string{"first", "second"}, wantCalls: 1, wantCached: true},
+		{name: "forced", responses: []string{"first", "second"}, force: true, wantCalls: 2},
+		{name: "empty responses aren't cached", responses: []string{" ", "second"}, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := NewFakeGenerator(tt.responses...)
+			g := NewCachingGenerator(next, NewMemoryCache(10), time.Hour)
+			req := TextGenerationRequest{Messages: []ChatMessage{{Role: RoleUser, Content: "prompt"}}}
+
+			first, err := g.Generate(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := g.Generate(bypassCache(context.Background(), tt.force), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(next.Requests) != tt.wantCalls {
+				t.Errorf("made %d provider calls, want %d", len(next.Requests), tt.wantCalls)
+			}
+			if first.Cached || second.Cached != tt.wantCached {
+				t.Errorf("Cached = %v, %v, want false, %v", first.Cached, second.Cached, tt.wantCached)
+			}
+			if tt.wantCached && second.Content != first.Content {
+				t.Errorf("cached response = %q, want %q", second.Content, first.Content)
+			}
+		})
+	}
+}
+
+func TestCachingGeneratorKey(t *testing.T) {
+	g := NewCachingGenerator(NewFakeGenerator(), NewMemoryCache(10), time.Hour)
+	ctx := context.Background()
+	req := TextGenerationRequest{Messages: []ChatMessage{{Role: RoleUser, Content: "prompt"}}, MaxTokens: 100}
+
+	other := req
+	other.MaxTokens = 200
+	if g.key(ctx, req) == g.key(ctx, other) {
+		t.Error("requests with different MaxTokens share a cache key")
+	}
+
+	other = req
+	other.Messages = []ChatMessage{{Role: RoleUser, Content: "other prompt"}}
+	if g.key(ctx, req) == g.key(ctx, other) {
+		t.Error("requests with different prompts share a cache key")
+	}
+
+	if g.key(ctx, req) != g.key(ctx, req) {
+		t.Error("the same request gets different cache keys")
+	}
+}
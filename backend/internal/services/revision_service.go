@@ -0,0 +1,320 @@
+package services
+
+import (
+	"strings"
+
+	"ai-blog-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type RevisionService struct {
+	db          *gorm.DB
+	blogService *BlogService
+}
+
+func NewRevisionService(db *gorm.DB, blogService *BlogService) *RevisionService {
+	return &RevisionService{db: db, blogService: blogService}
+}
+
+// DiffLine is a single line of a line-level diff between two revisions
+type DiffLine struct {
+	Type    string `json:"type"` // equal, added, removed
+	Text    string `json:"text"`
+	OldLine int    `json:"oldLine,omitempty"` // 1-based line number in the older revision
+	NewLine int    `json:"newLine,omitempty"` // 1-based line number in the newer revision
+}
+
+type RevisionDiff struct {
+	BlogID       string     `json:"blogId"`
+	From         int        `json:"from"`
+	To           int        `json:"to"`
+	TitleChanged bool       `json:"titleChanged"`
+	FromTitle    string     `json:"fromTitle"`
+	ToTitle      string     `json:"toTitle"`
+	Added        int        `json:"added"`
+	Removed      int        `json:"removed"`
+	Lines        []DiffLine `json:"lines"`
+}
+
+// ListRevisions returns the revision history of a blog, newest first, without content
+func (s *RevisionService) ListRevisions(blogID, authorID string) ([]models.BlogRevision, error) {
+	if err := s.ensureAuthor(blogID, authorID); err != nil {
+		return nil, err
+	}
+
+	var revisions []models.BlogRevision
+	err := s.db.Omit("content").
+		Where("blog_id = ?", blogID).
+		Order("revision DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+// GetRevision returns a single revision of a blog
+func (s *RevisionService) GetRevision(blogID string, revision int, authorID string) (*models.BlogRevision, error) {
+	if err := s.ensureAuthor(blogID, authorID); err != nil {
+		return nil, err
+	}
+
+	var rev models.BlogRevision
+	err := s.db.Where("blog_id = ? AND revision = ?", blogID, revision).First(&rev).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// DiffRevisions computes a line-level diff of the content between two revisions
+func (s *RevisionService) DiffRevisions(blogID string, from, to int, authorID string) (*RevisionDiff, error) {
+	fromRev, err := s.GetRevision(blogID, from, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	toRev, err := s.GetRevision(blogID, to, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &RevisionDiff{
+		BlogID:       blogID,
+		From:         from,
+		To:           to,
+		TitleChanged: fromRev.Title != toRev.Title,
+		FromTitle:    fromRev.Title,
+		ToTitle:      toRev.Title,
+		Lines:        diffLines(fromRev.Content, toRev.Content),
+	}
+
+	for _, line := range diff.Lines {
+		switch line.Type {
+		case "added":
+			diff.Added++
+		case "removed":
+			diff.Removed++
+		}
+	}
+
+	return diff, nil
+}
+
+// RestoreRevision makes an older revision the current content of the blog.
+// The blog keeps its current status and schedule, and the restore is itself recorded
+// as a new revision.
+func (s *RevisionService) RestoreRevision(blogID string, revision int, authorID string) (*models.Blog, error) {
+	rev, err := s.GetRevision(blogID, revision, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var blog models.Blog
+	err = s.db.Where("id = ? AND author_id = ?", blogID, authorID).First(&blog).Error
+	if err != nil {
+		return nil, err
+	}
+
+	req := models.CreateBlogRequest{
+		Title:           rev.Title,
+		Content:         rev.Content,
+		Description:     rev.Excerpt,
+		Tags:            rev.Tags,
+		Status:          blog.Status,
+		ScheduledAt:     blog.ScheduledAt,
+		MetaTitle:       rev.MetaTitle,
+		MetaDescription: rev.MetaDescription,
+		FeaturedImage:   rev.FeaturedImage,
+	}
+
+	return s.blogService.UpdateBlog(blogID, req, authorID)
+}
+
+// ensureAuthor returns gorm.ErrRecordNotFound unless the blog belongs to the author
+func (s *RevisionService) ensureAuthor(blogID, authorID string) error {
+	var count int64
+	err := s.db.Model(&models.Blog{}).
+		Where("id = ? AND author_id = ?", blogID, authorID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// snapshotRevision records the current state of a blog as its next revision
+func snapshotRevision(tx *gorm.DB, blog *models.Blog) error {
+	var latest int
+	err := tx.Model(&models.BlogRevision{}).
+		Select("COALESCE(MAX(revision), 0)").
+		Where("blog_id = ?", blog.ID).
+		Scan(&latest).Error
+	if err != nil {
+		return err
+	}
+
+	revision := models.BlogRevision{
+		BlogID:          blog.ID,
+		Revision:        latest + 1,
+		AuthorID:        blog.AuthorID,
+		Title:           blog.Title,
+		Content:         blog.Content,
+		Excerpt:         blog.Excerpt,
+		Slug:            blog.Slug,
+		Status:          blog.Status,
+		Tags:            blog.Tags,
+		MetaTitle:       blog.MetaTitle,
+		MetaDescription: blog.MetaDescription,
+		FeaturedImage:   blog.FeaturedImage,
+	}
+
+	return tx.Create(&revision).Error
+}
+
+// maxDiffEdits bounds the work done by diffLines. Texts that differ in more lines
+// than this are shown with their changed middle removed and re-added as a whole.
+const maxDiffEdits = 1000
+
+// Steps of a line edit script
+const (
+	editEqual byte = iota
+	editRemove
+	editAdd
+)
+
+// diffLines returns a line-level diff of two texts, using Myers' algorithm on the
+// lines between their common prefix and suffix
+func diffLines(oldText, newText string) []DiffLine {
+	oldLines := strings.Split(oldText, "\n")
+	newLines := strings.Split(newText, "\n")
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	oldMiddle := oldLines[prefix : len(oldLines)-suffix]
+	newMiddle := newLines[prefix : len(newLines)-suffix]
+	script, ok := shortestEdit(oldMiddle, newMiddle)
+	if !ok {
+		script = make([]byte, 0, len(oldMiddle)+len(newMiddle))
+		for range oldMiddle {
+			script = append(script, editRemove)
+		}
+		for range newMiddle {
+			script = append(script, editAdd)
+		}
+	}
+
+	lines := make([]DiffLine, 0, prefix+len(script)+suffix)
+	i, j := 0, 0
+	equal := func() {
+		lines = append(lines, DiffLine{Type: "equal", Text: oldLines[i], OldLine: i + 1, NewLine: j + 1})
+		i++
+		j++
+	}
+	for i < prefix {
+		equal()
+	}
+	for _, step := range script {
+		switch step {
+		case editEqual:
+			equal()
+		case editRemove:
+			lines = append(lines, DiffLine{Type: "removed", Text: oldLines[i], OldLine: i + 1})
+			i++
+		case editAdd:
+			lines = append(lines, DiffLine{Type: "added", Text: newLines[j], NewLine: j + 1})
+			j++
+		}
+	}
+	for i < len(oldLines) {
+		equal()
+	}
+
+	return lines
+}
+
+// shortestEdit returns the shortest edit script turning a into b (Myers, 1986),
+// or false if it takes more than maxDiffEdits removals and additions
+func shortestEdit(a, b []string) ([]byte, bool) {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > maxDiffEdits {
+		limit = maxDiffEdits
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k = x - y; trace[d] keeps
+	// diagonals -d..d of v after d edits, for walking back along the path
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Addition
+			} else {
+				x = v[offset+k-1] + 1 // Removal
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+				return editScript(trace, n, m), true
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	return nil, false
+}
+
+// editScript walks back from (n, m) through the trace recorded by shortestEdit
+func editScript(trace [][]int, n, m int) []byte {
+	var script []byte
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1] // prev[k+d-1] is the x reached on diagonal k
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			script = append(script, editEqual)
+			x--
+			y--
+		}
+		if x == prevX {
+			script = append(script, editAdd)
+			y--
+		} else {
+			script = append(script, editRemove)
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		script = append(script, editEqual)
+		x--
+		y--
+	}
+
+	for l, r := 0, len(script)-1; l < r; l, r = l+1, r-1 {
+		script[l], script[r] = script[r], script[l]
+	}
+	return script
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []DiffLine
+	}{
+		{
+			name: "identical",
+			old:  "a\nb",
+			new:  "a\nb",
+			want: []DiffLine{
+				{Type: "equal", Text: "a", OldLine: 1, NewLine: 1},
+				{Type: "equal", Text: "b", OldLine: 2, NewLine: 2},
+			},
+		},
+		{
+			name: "line added",
+			old:  "a\nc",
+			new:  "a\nb\nc",
+			want: []DiffLine{
+				{Type: "equal", Text: "a", OldLine: 1, NewLine: 1},
+				{Type: "added", Text: "b", NewLine: 2},
+				{Type: "equal", Text: "c", OldLine: 2, NewLine: 3},
+			},
+		},
+		{
+			name: "line removed",
+			old:  "a\nb\nc",
+			new:  "a\nb",
+			want: []DiffLine{
+				{Type: "equal", Text: "a", OldLine: 1, NewLine: 1},
+				{Type: "equal", Text: "b", OldLine: 2, NewLine: 2},
+				{Type: "removed", Text: "c", OldLine: 3},
+			},
+		},
+		{
+			name: "line changed",
+			old:  "a\nb\nc",
+			new:  "a\nx\nc",
+			want: []DiffLine{
+				{Type: "equal", Text: "a", OldLine: 1, NewLine: 1},
+				{Type: "removed", Text: "b", OldLine: 2},
+				{Type: "added", Text: "x", NewLine: 2},
+				{Type: "equal", Text: "c", OldLine: 3, NewLine: 3},
+			},
+		},
+		{
+			name: "line moved",
+			old:  "a\nb\nc\nd",
+			new:  "b\nc\nd\na",
+			want: []DiffLine{
+				{Type: "removed", Text: "a", OldLine: 1},
+				{Type: "equal", Text: "b", OldLine: 2, NewLine: 1},
+				{Type: "equal", Text: "c", OldLine: 3, NewLine: 2},
+				{Type: "equal", Text: "d", OldLine: 4, NewLine: 3},
+				{Type: "added", Text: "a", NewLine: 4},
+			},
+		},
+		{
+			name: "from an empty post",
+			old:  "",
+			new:  "a",
+			want: []DiffLine{
+				{Type: "removed", Text: "", OldLine: 1},
+				{Type: "added", Text: "a", NewLine: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffLines(tt.old, tt.new)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines(%q, %q) = %+v, want %+v", tt.old, tt.new, got, tt.want)
+			}
+		})
+	}
+}
+
+// Each side of a diff, read back without the other side's lines, is that side's text
+func TestDiffLinesSides(t *testing.T) {
+	tests := []struct {
+		old, new  string
+		wantEqual int // Length of the longest common subsequence
+	}{
+		{old: "a\nb\nc\na\nb\nb\na", new: "c\nb\na\nb\na\nc", wantEqual: 4},
+		{old: "a\nb\nc", new: "x\ny", wantEqual: 0},
+		{old: "h\n1\n2\nf", new: "h\n3\nf", wantEqual: 2},
+		// Too many edits to search for the shortest; only the common ends are kept
+		{old: "h\n" + numberedLines("old", maxDiffEdits) + "\nf", new: "h\n" + numberedLines("new", maxDiffEdits) + "\nf", wantEqual: 2},
+	}
+
+	for _, tt := range tests {
+		var oldSide, newSide []string
+		equal := 0
+		for _, line := range diffLines(tt.old, tt.new) {
+			if line.Type != "added" {
+				oldSide = append(oldSide, line.Text)
+			}
+			if line.Type != "removed" {
+				newSide = append(newSide, line.Text)
+			}
+			if line.Type == "equal" {
+				equal++
+			}
+		}
+
+		if got := strings.Join(oldSide, "\n"); got != tt.old {
+			t.Errorf("diffLines(%q, %q) old side = %q", tt.old, tt.new, got)
+		}
+		if got := strings.Join(newSide, "\n"); got != tt.new {
+			t.Errorf("diffLines(%q, %q) new side = %q", tt.old, tt.new, got)
+		}
+		if equal != tt.wantEqual {
+			t.Errorf("diffLines(%q, %q) kept %d lines, want %d", tt.old, tt.new, equal, tt.wantEqual)
+		}
+	}
+}
+
+func numberedLines(prefix string, n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%s %d", prefix, i)
+	}
+	return strings.Join(lines, "\n")
+}
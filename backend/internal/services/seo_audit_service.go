@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"ai-blog-backend/internal/models"
+)
+
+// Severities of SEO audit findings, from most to least important
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// severityPenalty is how many points each finding takes off the audit score
+var severityPenalty = map[string]int{
+	SeverityError:   15,
+	SeverityWarning: 8,
+	SeverityInfo:    3,
+}
+
+const (
+	titleMinLength = 20
+	titleMaxLength = 70
+	// minAuditWords is the length below which a post is considered thin content
+	minAuditWords = 300
+	// Keyword density range in percent of all words
+	minKeywordDensity = 0.5
+	maxKeywordDensity = 3.0
+	// maxReadabilityGrade is the highest Flesch-Kincaid grade we recommend for posts
+	maxReadabilityGrade = 10.0
+)
+
+type SEOAuditRequest struct {
+	Keyword         string `json:"keyword"`         // Defaults to the post's first tag
+	SkipSuggestions bool   `json:"skipSuggestions"` // Run only the deterministic checks, without AI
+	Force           bool   `json:"force"`           // Skip cached AI suggestions
+}
+
+// SEOFix is a one-click fix: the post field to change and its new value.
+// Fields use the names of CreateBlogRequest.
+type SEOFix struct {
+	Field string      `json:"field"`
+	Value interface{} `json:"value"`
+}
+
+type SEOFinding struct {
+	Check    string  `json:"check"`
+	Severity string  `json:"severity"` // error, warning or info
+	Message  string  `json:"message"`
+	Fix      *SEOFix `json:"fix,omitempty"`
+}
+
+type SEOStats struct {
+	WordCount        int     `json:"wordCount"`
+	Keyword          string  `json:"keyword"`
+	KeywordDensity   float64 `json:"keywordDensity"` // Percent of all words
+	ReadabilityGrade float64 `json:"readabilityGrade"`
+	InternalLinks    int     `json:"internalLinks"`
+	ExternalLinks    int     `json:"externalLinks"`
+	Images           int     `json:"images"`
+	ImagesMissingAlt int     `json:"imagesMissingAlt"`
+}
+
+// SEOSuggestions are AI-written replacements for a post's SEO fields
+type SEOSuggestions struct {
+	MetaTitle       string   `json:"metaTitle"`
+	MetaDescription string   `json:"metaDescription"`
+	Tags            []string `json:"tags"`
+}
+
+type SEOAuditResponse struct {
+	Score            int             `json:"score"` // 0-100
+	Findings         []SEOFinding    `json:"findings"`
+	Stats            SEOStats        `json:"stats"`
+	Suggestions      *SEOSuggestions `json:"suggestions,omitempty"`
+	SuggestionsError string          `json:"suggestionsError,omitempty"`
+	Usage            TokenUsage      `json:"usage"`
+}
+
+// SEOAuditService reviews posts before they are published
+type SEOAuditService struct {
+	aiService *AIService
+	siteURL   string
+}
+
+func NewSEOAuditService(aiService *AIService, siteURL string) *SEOAuditService {
+	return &SEOAuditService{aiService: aiService, siteURL: siteURL}
+}
+
+// Audit scores a post's SEO. AI suggestions are optional: if they fail the audit
+// is still returned, with the reason in SuggestionsError.
+func (s *SEOAuditService) Audit(ctx context.Context, blog *models.Blog, req SEOAuditRequest) *SEOAuditResponse {
+	keyword := strings.TrimSpace(req.Keyword)
+	if keyword == "" && len(blog.Tags) > 0 {
+		keyword = blog.Tags[0]
+	}
+
+	audit := &seoAudit{blog: blog, siteURL: s.siteURL, keyword: keyword}
+	audit.run()
+
+	response := &SEOAuditResponse{Findings: audit.findings, Stats: audit.stats}
+	if !req.SkipSuggestions {
+		meta, err := s.aiService.GenerateMeta(ctx, GenerateMetaRequest{Title: blog.Title, Content: blog.Content, Force: req.Force})
+		if err != nil {
+			log.Printf("Warning: Could not get SEO suggestions for blog %s: %v", blog.ID, err)
+			response.SuggestionsError = err.Error()
+		} else {
+			response.Suggestions = &SEOSuggestions{MetaTitle: meta.MetaTitle, MetaDescription: meta.MetaDescription, Tags: meta.Tags}
+			response.Usage = meta.Usage
+			attachSuggestions(response.Findings, response.Suggestions)
+		}
+	}
+
+	response.Score = 100
+	for _, finding := range response.Findings {
+		response.Score -= severityPenalty[finding.Severity]
+	}
+	if response.Score < 0 {
+		response.Score = 0
+	}
+	return response
+}
+
+// attachSuggestions offers the AI suggestions as fixes for findings that have none yet
+func attachSuggestions(findings []SEOFinding, suggestions *SEOSuggestions) {
+	for i := range findings {
+		if findings[i].Fix != nil {
+			continue
+		}
+		switch findings[i].Check {
+		case "title_length":
+			findings[i].Fix = &SEOFix{Field: "title", Value: suggestions.MetaTitle}
+		case "meta_title":
+			findings[i].Fix = &SEOFix{Field: "metaTitle", Value: suggestions.MetaTitle}
+		case "meta_description", "keyword_in_description":
+			findings[i].Fix = &SEOFix{Field: "metaDescription", Value: suggestions.MetaDescription}
+		case "keyword":
+			findings[i].Fix = &SEOFix{Field: "tags", Value: suggestions.Tags}
+		}
+	}
+}
+
+// seoAudit holds the state of one run of the deterministic checks
+type seoAudit struct {
+	blog     *models.Blog
+	siteURL  string
+	keyword  string
+	words    []string
+	findings []SEOFinding
+	stats    SEOStats
+}
+
+func (a *seoAudit) add(check, severity, message string, fix *SEOFix) {
+	a.findings = append(a.findings, SEOFinding{Check: check, Severity: severity, Message: message, Fix: fix})
+}
+
+func (a *seoAudit) run() {
+	text := plainText(a.blog.Content)
+	a.words = wordsOf(text)
+	a.stats.WordCount = len(a.words)
+	a.findings = []SEOFinding{}
+
+	elements := inspectMarkdown(a.blog.Content)
+
+	a.checkTitle()
+	a.checkMeta()
+	a.checkLength()
+	a.checkHeadings(elements.Headings)
+	a.checkKeyword(elements.Headings)
+	a.checkImages(elements.Images)
+	a.checkLinks(elements.Links)
+	a.checkReadability(text)
+}
+
+func (a *seoAudit) checkTitle() {
+	length := utf8.RuneCountInString(a.blog.Title)
+	if length < titleMinLength || length > titleMaxLength {
+		a.add("title_length", SeverityWarning,
+			fmt.Sprintf("Title is %d characters; %d-%d reads best in search results", length, titleMinLength, titleMaxLength), nil)
+	}
+}
+
+func (a *seoAudit) checkMeta() {
+	metaTitle := strings.TrimSpace(a.blog.MetaTitle)
+	if metaTitle == "" {
+		a.add("meta_title", SeverityError, "Meta title is missing",
+			&SEOFix{Field: "metaTitle", Value: truncateText(a.blog.Title, metaTitleMaxLength)})
+	} else if length := utf8.RuneCountInString(metaTitle); length < metaTitleMinLength || length > metaTitleMaxLength {
+		a.add("meta_title", SeverityWarning,
+			fmt.Sprintf("Meta title is %d characters; aim for %d-%d", length, metaTitleMinLength, metaTitleMaxLength), nil)
+	}
+
+	description := strings.TrimSpace(a.blog.MetaDescription)
+	if description == "" {
+		var fix *SEOFix
+		if a.blog.Excerpt != "" {
+			fix = &SEOFix{Field: "metaDescription", Value: truncateText(a.blog.Excerpt, metaDescriptionMaxLength)}
+		}
+		a.add("meta_description", SeverityError, "Meta description is missing", fix)
+	} else if length := utf8.RuneCountInString(description); length < metaDescriptionMinLength || length > metaDescriptionMaxLength {
+		a.add("meta_description", SeverityWarning,
+			fmt.Sprintf("Meta description is %d characters; aim for %d-%d", length, metaDescriptionMinLength, metaDescriptionMaxLength), nil)
+	}
+}
+
+func (a *seoAudit) checkLength() {
+	if a.stats.WordCount < minAuditWords {
+		a.add("word_count", SeverityWarning,
+			fmt.Sprintf("Post has %d words; posts under %d words rarely rank well", a.stats.WordCount, minAuditWords), nil)
+	}
+}
+
+// checkHeadings expects sections to start at level 2, since the title is the
+// page's only level 1 heading, and never to skip a level on the way down
+func (a *seoAudit) checkHeadings(headings []markdownHeading) {
+	if len(headings) == 0 {
+		if a.stats.WordCount >= minAuditWords {
+			a.add("headings", SeverityWarning, "Post has no subheadings; break long posts into sections with ## headings", nil)
+		}
+		return
+	}
+
+	previous := 1
+	for _, heading := range headings {
+		if heading.Level == 1 {
+			a.add("heading_hierarchy", SeverityWarning,
+				fmt.Sprintf("Heading %q is level 1; the title is already the page's level 1 heading, so use ## instead", heading.Text), nil)
+		} else if heading.Level > previous+1 {
+			a.add("heading_hierarchy", SeverityInfo,
+				fmt.Sprintf("Heading %q jumps from level %d to level %d", heading.Text, previous, heading.Level), nil)
+		}
+		previous = heading.Level
+	}
+}
+
+func (a *seoAudit) checkKeyword(headings []markdownHeading) {
+	a.stats.Keyword = a.keyword
+	keywordWords := wordsOf(a.keyword)
+	if len(keywordWords) == 0 {
+		a.add("keyword", SeverityWarning, "No focus keyword; pass one or add tags to the post", nil)
+		return
+	}
+
+	if a.stats.WordCount > 0 {
+		count := countPhrase(a.words, keywordWords)
+		density := float64(count*len(keywordWords)) * 100 / float64(a.stats.WordCount)
+		a.stats.KeywordDensity = float64(int(density*100+0.5)) / 100
+	}
+	switch {
+	case a.stats.KeywordDensity < minKeywordDensity:
+		a.add("keyword_density", SeverityWarning,
+			fmt.Sprintf("Keyword %q makes up %.1f%% of the text; aim for %.1f-%.1f%%", a.keyword, a.stats.KeywordDensity, minKeywordDensity, maxKeywordDensity), nil)
+	case a.stats.KeywordDensity > maxKeywordDensity:
+		a.add("keyword_density", SeverityWarning,
+			fmt.Sprintf("Keyword %q makes up %.1f%% of the text, which can read as keyword stuffing; aim for %.1f-%.1f%%", a.keyword, a.stats.KeywordDensity, minKeywordDensity, maxKeywordDensity), nil)
+	}
+
+	if countPhrase(wordsOf(a.blog.Title), keywordWords) == 0 {
+		a.add("keyword_in_title", SeverityWarning, fmt.Sprintf("Title doesn't contain the keyword %q", a.keyword), nil)
+	}
+	if a.blog.MetaDescription != "" && countPhrase(wordsOf(a.blog.MetaDescription), keywordWords) == 0 {
+		a.add("keyword_in_description", SeverityInfo, fmt.Sprintf("Meta description doesn't contain the keyword %q", a.keyword), nil)
+	}
+
+	inHeading := false
+	for _, heading := range headings {
+		if countPhrase(wordsOf(heading.Text), keywordWords) > 0 {
+			inHeading = true
+			break
+		}
+	}
+	if len(headings) > 0 && !inHeading {
+		a.add("keyword_in_headings", SeverityInfo, fmt.Sprintf("No subheading contains the keyword %q", a.keyword), nil)
+	}
+}
+
+func (a *seoAudit) checkImages(images []markdownImage) {
+	if strings.TrimSpace(a.blog.FeaturedImage) == "" {
+		a.add("featured_image", SeverityWarning, "Post has no featured image, so social shares will show no preview", nil)
+	}
+
+	a.stats.Images = len(images)
+	for _, image := range images {
+		if image.Alt == "" {
+			a.stats.ImagesMissingAlt++
+		}
+	}
+	if a.stats.ImagesMissingAlt > 0 {
+		a.add("image_alt", SeverityWarning,
+			fmt.Sprintf("%d of %d images have no alt text", a.stats.ImagesMissingAlt, a.stats.Images), nil)
+	}
+}
+
+func (a *seoAudit) checkLinks(links []string) {
+	var siteHost string
+	if site, err := url.Parse(a.siteURL); err == nil {
+		siteHost = site.Host
+	}
+
+	for _, link := range links {
+		switch {
+		case strings.HasPrefix(link, "#"):
+			// Anchors within the post
+		case strings.HasPrefix(link, "/") && !strings.HasPrefix(link, "//"):
+			a.stats.InternalLinks++
+		default:
+			if parsed, err := url.Parse(link); err == nil && parsed.Host != "" && parsed.Host == siteHost {
+				a.stats.InternalLinks++
+			} else {
+				a.stats.ExternalLinks++
+			}
+		}
+	}
+
+	if a.stats.InternalLinks == 0 {
+		a.add("internal_links", SeverityWarning, "Post doesn't link to any other posts on the blog", nil)
+	}
+}
+
+// checkReadability computes the Flesch-Kincaid grade level, which is only
+// meaningful for English posts
+func (a *seoAudit) checkReadability(text string) {
+	if a.blog.Locale != "" && !strings.HasPrefix(a.blog.Locale, "en") {
+		return
+	}
+
+	var sentences, syllables int
+	for _, line := range strings.Split(text, "\n") {
+		sentences += len(splitSentences(line))
+	}
+	for _, word := range a.words {
+		syllables += countSyllables(word)
+	}
+	if sentences == 0 || len(a.words) == 0 {
+		return
+	}
+
+	grade := 0.39*float64(len(a.words))/float64(sentences) + 11.8*float64(syllables)/float64(len(a.words)) - 15.59
+	if grade < 0 {
+		grade = 0
+	}
+	a.stats.ReadabilityGrade = float64(int(grade*10+0.5)) / 10
+
+	if a.stats.ReadabilityGrade > maxReadabilityGrade {
+		a.add("readability", SeverityInfo,
+			fmt.Sprintf("Text reads at grade %.1f; shorter sentences and simpler words would bring it to %.0f or below", a.stats.ReadabilityGrade, maxReadabilityGrade), nil)
+	}
+}
+
+// countPhrase counts the occurrences of a sequence of words
+func countPhrase(words, phrase []string) int {
+	count := 0
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		match := true
+		for j, word := range phrase {
+			if stemWord(words[i+j]) != stemWord(word) {
+				match = false
+				break
+			}
+		}
+		if match {
+			count++
+		}
+	}
+	return count
+}
+
+// countSyllables estimates the syllables of an English word by counting vowel groups
+func countSyllables(word string) int {
+	count := 0
+	previousVowel := false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !previousVowel {
+			count++
+		}
+		previousVowel = vowel
+	}
+	if strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && count > 1 {
+		count--
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
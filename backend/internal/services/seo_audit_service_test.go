@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ai-blog-backend/internal/models"
+)
+
+const seoTestSiteURL = "https://blog.example.com"
+
+// seoTestBlog returns a post that passes every check for its tag "go testing"
+func seoTestBlog() *models.Blog {
+	paragraph := strings.Repeat("We write small tests for each part of the code. ", 16)
+	return &models.Blog{
+		Title:           "A practical guide to go testing",
+		MetaTitle:       "A practical guide to go testing for busy developers",
+		MetaDescription: "Learn how go testing keeps your code honest, with small focused tests, table-driven cases and fast feedback that catches regressions long before users ever do.",
+		FeaturedImage:   "https://cdn.example.com/cover.png",
+		Locale:          "en",
+		Tags:            []string{"go testing", "go"},
+		Content: "## Why go testing pays off\n\n" + paragraph +
+			"\n\n## Writing your first go testing suite\n\n" + paragraph +
+			"\n\n![A test run](/img/run.png)\n\nRead [more posts](/blog) next.\n",
+	}
+}
+
+func auditChecks(response *SEOAuditResponse) []string {
+	checks := []string{}
+	for _, finding := range response.Findings {
+		checks = append(checks, finding.Check+"/"+finding.Severity)
+	}
+	return checks
+}
+
+func TestSEOAudit(t *testing.T) {
+	hardToRead := func(b *models.Blog) {
+		b.Content = strings.ReplaceAll(b.Content, "We write small tests for each part of the code.",
+			"Comprehensive automated verification methodologies substantially improve organizational development reliability.")
+	}
+
+	tests := []struct {
+		name    string
+		keyword string
+		edit    func(*models.Blog)
+		want    []string
+	}{
+		{name: "good post", edit: func(b *models.Blog) {}, want: []string{}},
+		{name: "short title", edit: func(b *models.Blog) { b.Title = "Go testing" }, want: []string{"title_length/warning"}},
+		{name: "no meta title", edit: func(b *models.Blog) { b.MetaTitle = "" }, want: []string{"meta_title/error"}},
+		{name: "short meta title", edit: func(b *models.Blog) { b.MetaTitle = "Go testing guide" }, want: []string{"meta_title/warning"}},
+		{name: "no meta description", edit: func(b *models.Blog) { b.MetaDescription = "" }, want: []string{"meta_description/error"}},
+		{
+			name: "short meta description without the keyword",
+			edit: func(b *models.Blog) { b.MetaDescription = "A short description." },
+			want: []string{"meta_description/warning", "keyword_in_description/info"},
+		},
+		{
+			name: "thin content",
+			edit: func(b *models.Blog) {
+				b.Content = "## Go testing\n\nGo testing is fun. Read [more posts](/blog) and see ![a run](/run.png)."
+			},
+			want: []string{"word_count/warning", "keyword_density/warning"},
+		},
+		{
+			name: "long post without subheadings",
+			edit: func(b *models.Blog) { b.Content = strings.ReplaceAll(b.Content, "## ", "") },
+			want: []string{"headings/warning"},
+		},
+		{
+			name: "level 1 heading",
+			edit: func(b *models.Blog) { b.Content = strings.Replace(b.Content, "## Why", "# Why", 1) },
+			want: []string{"heading_hierarchy/warning"},
+		},
+		{
+			name: "skipped heading level",
+			edit: func(b *models.Blog) { b.Content = strings.Replace(b.Content, "## Writing", "#### Writing", 1) },
+			want: []string{"heading_hierarchy/info"},
+		},
+		{name: "no keyword or tags", edit: func(b *models.Blog) { b.Tags = nil }, want: []string{"keyword/warning"}},
+		{
+			name:    "keyword missing everywhere",
+			keyword: "small tests",
+			edit:    func(b *models.Blog) { b.Content = strings.ReplaceAll(b.Content, "small tests", "checks") },
+			want:    []string{"keyword_density/warning", "keyword_in_title/warning", "keyword_in_description/info", "keyword_in_headings/info"},
+		},
+		{
+			name:    "keyword stuffing",
+			keyword: "code",
+			edit:    func(b *models.Blog) { b.Title = "Code you can trust: a guide" },
+			want:    []string{"keyword_density/warning", "keyword_in_headings/info"},
+		},
+		{
+			name: "keyword in another word form",
+			edit: func(b *models.Blog) { b.Title = "A practical guide to Go tests" },
+			want: []string{},
+		},
+		{name: "no featured image", edit: func(b *models.Blog) { b.FeaturedImage = " " }, want: []string{"featured_image/warning"}},
+		{
+			name: "image without alt text",
+			edit: func(b *models.Blog) { b.Content = strings.Replace(b.Content, "A test run", "", 1) },
+			want: []string{"image_alt/warning"},
+		},
+		{
+			name: "no internal links",
+			edit: func(b *models.Blog) { b.Content = strings.Replace(b.Content, "(/blog)", "(https://go.dev/blog)", 1) },
+			want: []string{"internal_links/warning"},
+		},
+		{name: "hard to read", edit: hardToRead, want: []string{"readability/info"}},
+		{
+			name: "hard to read in German",
+			edit: func(b *models.Blog) { hardToRead(b); b.Locale = "de" },
+			want: []string{},
+		},
+	}
+
+	s := NewSEOAuditService(nil, seoTestSiteURL)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blog := seoTestBlog()
+			tt.edit(blog)
+
+			response := s.Audit(context.Background(), blog, SEOAuditRequest{Keyword: tt.keyword, SkipSuggestions: true})
+			if got := auditChecks(response); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSEOAuditScore(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(*models.Blog)
+		want int
+	}{
+		{name: "good post", edit: func(b *models.Blog) {}, want: 100},
+		{name: "one error", edit: func(b *models.Blog) { b.MetaTitle = "" }, want: 85},
+		{name: "error and warning", edit: func(b *models.Blog) { b.MetaTitle, b.FeaturedImage = "", "" }, want: 77},
+		{
+			name: "never below zero",
+			edit: func(b *models.Blog) {
+				*b = models.Blog{Content: "# A\n\n#### B\n\n##### C\n\n###### D\n\n# E\n\n# F\n\n# G"}
+			},
+			want: 0,
+		},
+	}
+
+	s := NewSEOAuditService(nil, seoTestSiteURL)
+	for _, tt := range tests {
+		blog := seoTestBlog()
+		tt.edit(blog)
+
+		response := s.Audit(context.Background(), blog, SEOAuditRequest{SkipSuggestions: true})
+		if response.Score != tt.want {
+			t.Errorf("%s: Score = %d, want %d (findings %v)", tt.name, response.Score, tt.want, auditChecks(response))
+		}
+	}
+}
+
+func TestSEOAuditFixes(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(*models.Blog)
+		check string
+		want  *SEOFix
+	}{
+		{
+			name:  "meta title from the title",
+			edit:  func(b *models.Blog) { b.MetaTitle = "" },
+			check: "meta_title",
+			want:  &SEOFix{Field: "metaTitle", Value: "A practical guide to go testing"},
+		},
+		{
+			name:  "meta description from the excerpt",
+			edit:  func(b *models.Blog) { b.MetaDescription, b.Excerpt = "", "Why go testing matters." },
+			check: "meta_description",
+			want:  &SEOFix{Field: "metaDescription", Value: "Why go testing matters."},
+		},
+		{
+			name:  "no excerpt to use",
+			edit:  func(b *models.Blog) { b.MetaDescription = "" },
+			check: "meta_description",
+		},
+	}
+
+	s := NewSEOAuditService(nil, seoTestSiteURL)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blog := seoTestBlog()
+			tt.edit(blog)
+
+			response := s.Audit(context.Background(), blog, SEOAuditRequest{SkipSuggestions: true})
+			for _, finding := range response.Findings {
+				if finding.Check == tt.check {
+					if !reflect.DeepEqual(finding.Fix, tt.want) {
+						t.Errorf("fix = %+v, want %+v", finding.Fix, tt.want)
+					}
+					return
+				}
+			}
+			t.Errorf("no %s finding in %v", tt.check, auditChecks(response))
+		})
+	}
+}
+
+func TestSEOAuditLinks(t *testing.T) {
+	tests := []struct {
+		link         string
+		wantInternal int
+		wantExternal int
+	}{
+		{link: "/blog/other-post", wantInternal: 1},
+		{link: "https://blog.example.com/blog/other-post", wantInternal: 1},
+		{link: "https://go.dev/doc", wantExternal: 1},
+		{link: "//go.dev/doc", wantExternal: 1},
+		{link: "https://blog.example.com.evil.test/", wantExternal: 1},
+		{link: "#section"},
+	}
+
+	for _, tt := range tests {
+		audit := &seoAudit{blog: &models.Blog{}, siteURL: seoTestSiteURL}
+		audit.checkLinks([]string{tt.link})
+		if audit.stats.InternalLinks != tt.wantInternal || audit.stats.ExternalLinks != tt.wantExternal {
+			t.Errorf("checkLinks(%q) counted %d internal and %d external, want %d and %d",
+				tt.link, audit.stats.InternalLinks, audit.stats.ExternalLinks, tt.wantInternal, tt.wantExternal)
+		}
+	}
+}
+
+func TestCountPhrase(t *testing.T) {
+	tests := []struct {
+		text, phrase string
+		want         int
+	}{
+		{text: "go testing is go testing", phrase: "go testing", want: 2},
+		{text: "Go, testing!", phrase: "go testing", want: 1},
+		{text: "testing go", phrase: "go testing", want: 0},
+		{text: "caching tests", phrase: "cached test", want: 1},
+		{text: "go", phrase: "go testing", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := countPhrase(wordsOf(tt.text), wordsOf(tt.phrase)); got != tt.want {
+			t.Errorf("countPhrase(%q, %q) = %d, want %d", tt.text, tt.phrase, got, tt.want)
+		}
+	}
+}
+
+func TestCountSyllables(t *testing.T) {
+	tests := map[string]int{
+		"go":       1,
+		"code":     1,
+		"table":    2,
+		"testing":  2,
+		"reliable": 3,
+		"queue":    1,
+		"rhythm":   1,
+		"http":     1,
+	}
+
+	for word, want := range tests {
+		if got := countSyllables(word); got != want {
+			t.Errorf("countSyllables(%q) = %d, want %d", word, got, want)
+		}
+	}
+}
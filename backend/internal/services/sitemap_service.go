@@ -0,0 +1,332 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-blog-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SitemapURLLimit is the maximum number of URLs allowed in a single sitemap file
+const SitemapURLLimit = 50000
+
+// RobotsConfig controls the rules served in robots.txt
+type RobotsConfig struct {
+	DisallowAll bool     // Block all crawlers, e.g. on staging
+	Disallow    []string // Paths crawlers should skip
+	SitemapURL  string   // Absolute sitemap URL; the site URL + /sitemap.xml when empty
+}
+
+type SitemapService struct {
+	db     *gorm.DB
+	site   SiteConfig
+	robots RobotsConfig
+}
+
+func NewSitemapService(db *gorm.DB, site SiteConfig, robots RobotsConfig) *SitemapService {
+	site.URL = strings.TrimRight(site.URL, "/")
+	return &SitemapService{db: db, site: site, robots: robots}
+}
+
+// SitemapEntry is a single page listed in a sitemap
+type SitemapEntry struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type lastModRow struct {
+	Key     string
+	LastMod time.Time
+}
+
+// sitemapSection is a run of sitemap entries that can be counted and loaded in slices
+type sitemapSection struct {
+	count   func() (int, error)
+	load    func(offset, limit int) ([]SitemapEntry, error)
+	lastMod func(offset, limit int) (time.Time, error) // Latest LastMod of a slice, without loading it
+}
+
+// GetEntries lists the home page, every published blog, and the author and tag pages
+func (s *SitemapService) GetEntries() ([]SitemapEntry, error) {
+	return s.entries(0, -1)
+}
+
+// GetEntriesPage returns the entries of the 1-based sitemap page, loading only those
+func (s *SitemapService) GetEntriesPage(page int) ([]SitemapEntry, error) {
+	if page < 1 {
+		return nil, nil
+	}
+	return s.entries((page-1)*SitemapURLLimit, SitemapURLLimit)
+}
+
+// GetIndexPages returns the latest modification of each sitemap page, computed
+// without loading the entries, or nil if every entry fits in a single sitemap
+func (s *SitemapService) GetIndexPages() ([]time.Time, error) {
+	sections := s.sections()
+	counts := make([]int, len(sections))
+	total := 0
+	for i, section := range sections {
+		n, err := section.count()
+		if err != nil {
+			return nil, err
+		}
+		counts[i] = n
+		total += n
+	}
+	if total <= SitemapURLLimit {
+		return nil, nil
+	}
+
+	pages := make([]time.Time, SitemapPageCount(total))
+	start := 0 // Position of the section's first entry in the whole sitemap
+	for i, section := range sections {
+		// Split the section where it crosses into the next page
+		for offset := 0; offset < counts[i]; {
+			page := (start + offset) / SitemapURLLimit
+			limit := min((page+1)*SitemapURLLimit-(start+offset), counts[i]-offset)
+
+			lastMod, err := section.lastMod(offset, limit)
+			if err != nil {
+				return nil, err
+			}
+			if lastMod.After(pages[page]) {
+				pages[page] = lastMod
+			}
+			offset += limit
+		}
+		start += counts[i]
+	}
+	return pages, nil
+}
+
+// entries loads limit entries starting at offset, or all of them from offset if limit is negative
+func (s *SitemapService) entries(offset, limit int) ([]SitemapEntry, error) {
+	var entries []SitemapEntry
+	for _, section := range s.sections() {
+		if limit == 0 {
+			break
+		}
+		if offset > 0 {
+			n, err := section.count()
+			if err != nil {
+				return nil, err
+			}
+			if offset >= n {
+				offset -= n
+				continue
+			}
+		}
+
+		loaded, err := section.load(offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, loaded...)
+		offset = 0
+		if limit > 0 {
+			limit -= len(loaded)
+		}
+	}
+	return entries, nil
+}
+
+// sections returns the home page, published blogs, authors and tags, in sitemap order
+func (s *SitemapService) sections() []sitemapSection {
+	published := func() *gorm.DB {
+		return s.db.Model(&models.Blog{}).Where("status = ?", "published")
+	}
+	const tagsFrom = "blogs, unnest(tags) AS tag"
+	tags := func() *gorm.DB {
+		return s.db.Table(tagsFrom).Where("status = ? AND deleted_at IS NULL", "published")
+	}
+
+	homeLastMod := func(offset, limit int) (time.Time, error) {
+		var lastMod sql.NullTime
+		err := published().Select("MAX(updated_at)").Scan(&lastMod).Error
+		return lastMod.Time, err
+	}
+	home := sitemapSection{
+		count: func() (int, error) { return 1, nil },
+		load: func(offset, limit int) ([]SitemapEntry, error) {
+			lastMod, err := homeLastMod(offset, limit)
+			if err != nil {
+				return nil, err
+			}
+			return []SitemapEntry{{Loc: s.site.URL + "/", LastMod: lastMod}}, nil
+		},
+		lastMod: homeLastMod,
+	}
+
+	blogs := sitemapSection{
+		count: func() (int, error) {
+			var n int64
+			err := published().Count(&n).Error
+			return int(n), err
+		},
+		load: func(offset, limit int) ([]SitemapEntry, error) {
+			var blogs []models.Blog
+			err := published().Select("slug", "updated_at").
+				Order("published_at DESC, id").Offset(offset).Limit(limit).Find(&blogs).Error
+			if err != nil {
+				return nil, err
+			}
+			entries := make([]SitemapEntry, 0, len(blogs))
+			for _, blog := range blogs {
+				entries = append(entries, SitemapEntry{Loc: s.site.BlogURL(blog.Slug), LastMod: blog.UpdatedAt})
+			}
+			return entries, nil
+		},
+		lastMod: func(offset, limit int) (time.Time, error) {
+			return s.maxLastMod(published().Select("updated_at AS last_mod").
+				Order("published_at DESC, id").Offset(offset).Limit(limit))
+		},
+	}
+
+	authors := sitemapSection{
+		count: func() (int, error) {
+			var n int64
+			err := published().Distinct("author_id").Count(&n).Error
+			return int(n), err
+		},
+		load: func(offset, limit int) ([]SitemapEntry, error) {
+			var rows []lastModRow
+			err := published().Select("author_id AS key, MAX(updated_at) AS last_mod").
+				Group("author_id").Order("author_id").Offset(offset).Limit(limit).Scan(&rows).Error
+			if err != nil {
+				return nil, err
+			}
+			entries := make([]SitemapEntry, 0, len(rows))
+			for _, row := range rows {
+				entries = append(entries, SitemapEntry{Loc: s.site.AuthorURL(row.Key), LastMod: row.LastMod})
+			}
+			return entries, nil
+		},
+		lastMod: func(offset, limit int) (time.Time, error) {
+			return s.maxLastMod(published().Select("MAX(updated_at) AS last_mod").
+				Group("author_id").Order("author_id").Offset(offset).Limit(limit))
+		},
+	}
+
+	tagPages := sitemapSection{
+		count: func() (int, error) {
+			var n int64
+			err := tags().Select("COUNT(DISTINCT tag)").Scan(&n).Error
+			return int(n), err
+		},
+		load: func(offset, limit int) ([]SitemapEntry, error) {
+			var rows []lastModRow
+			err := tags().Select("tag AS key, MAX(updated_at) AS last_mod").
+				Group("tag").Order("tag").Offset(offset).Limit(limit).Scan(&rows).Error
+			if err != nil {
+				return nil, err
+			}
+			entries := make([]SitemapEntry, 0, len(rows))
+			for _, row := range rows {
+				entries = append(entries, SitemapEntry{Loc: s.site.TagURL(row.Key), LastMod: row.LastMod})
+			}
+			return entries, nil
+		},
+		lastMod: func(offset, limit int) (time.Time, error) {
+			return s.maxLastMod(tags().Select("MAX(updated_at) AS last_mod").
+				Group("tag").Order("tag").Offset(offset).Limit(limit))
+		},
+	}
+
+	return []sitemapSection{home, blogs, authors, tagPages}
+}
+
+// maxLastMod returns the latest last_mod among the rows selected by query
+func (s *SitemapService) maxLastMod(query *gorm.DB) (time.Time, error) {
+	var lastMod sql.NullTime
+	err := s.db.Table("(?) AS page", query).Select("MAX(last_mod)").Scan(&lastMod).Error
+	return lastMod.Time, err
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// RenderURLSet renders entries as a single sitemap
+func (s *SitemapService) RenderURLSet(entries []SitemapEntry) ([]byte, error) {
+	doc := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, entry := range entries {
+		doc.URLs = append(doc.URLs, sitemapURL{Loc: entry.Loc, LastMod: formatLastMod(entry.LastMod)})
+	}
+	return marshalXML(doc)
+}
+
+// RenderIndex renders a sitemap index pointing at /sitemaps/1.xml, 2.xml and so on
+// on the site, given the latest modification of each page
+func (s *SitemapService) RenderIndex(pages []time.Time) ([]byte, error) {
+	doc := sitemapIndex{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for i, lastMod := range pages {
+		doc.Sitemaps = append(doc.Sitemaps, sitemapURL{
+			Loc:     fmt.Sprintf("%s/sitemaps/%d.xml", s.site.URL, i+1),
+			LastMod: formatLastMod(lastMod),
+		})
+	}
+	return marshalXML(doc)
+}
+
+// RenderRobots renders robots.txt, pointing crawlers at the site's sitemap unless
+// another one is configured
+func (s *SitemapService) RenderRobots() string {
+	sitemapURL := s.site.URL + "/sitemap.xml"
+	if s.robots.SitemapURL != "" {
+		sitemapURL = s.robots.SitemapURL
+	}
+
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	if s.robots.DisallowAll {
+		b.WriteString("Disallow: /\n")
+	} else {
+		b.WriteString("Allow: /\n")
+		for _, path := range s.robots.Disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", path)
+		}
+	}
+	fmt.Fprintf(&b, "\nSitemap: %s\n", sitemapURL)
+	return b.String()
+}
+
+// SitemapPageCount returns how many sitemap files are needed for n entries
+func SitemapPageCount(n int) int {
+	return (n + SitemapURLLimit - 1) / SitemapURLLimit
+}
+
+// LatestModification returns the most recent LastMod among entries
+func LatestModification(entries []SitemapEntry) time.Time {
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.LastMod.After(latest) {
+			latest = entry.LastMod
+		}
+	}
+	return latest
+}
+
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ai-blog-backend/internal/models"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const (
+	SummarySourceAI         = "ai"
+	SummarySourceExtractive = "extractive"
+)
+
+const (
+	minTakeaways = 3
+	maxTakeaways = 5
+	// maxSummaryLength is the longest TL;DR we store, in characters
+	maxSummaryLength = 400
+	// maxTakeawayLength keeps extracted takeaways to a single short sentence, in characters
+	maxTakeawayLength = 200
+	// summaryRefreshDistance is how many of the 64 fingerprint bits may differ before
+	// a post counts as materially changed and its summary is regenerated
+	summaryRefreshDistance = 8
+)
+
+// BlogSummary is a post's TL;DR and key takeaways
+type BlogSummary struct {
+	Summary      string     `json:"summary"`
+	KeyTakeaways []string   `json:"keyTakeaways"`
+	Source       string     `json:"source"` // ai or extractive
+	Usage        TokenUsage `json:"usage"`
+}
+
+// SummaryService keeps the summaries of blogs up to date. Published posts are
+// summarized by the AI service when one is given; everything else, and any post
+// the AI can't summarize, gets an extractive summary built from its own sentences.
+type SummaryService struct {
+	db           *gorm.DB
+	aiService    *AIService
+	usageService *UsageService
+}
+
+func NewSummaryService(db *gorm.DB, aiService *AIService, usageService *UsageService) *SummaryService {
+	return &SummaryService{db: db, aiService: aiService, usageService: usageService}
+}
+
+// SummarizeBlog stores a new summary for the blog unless its current one was made
+// from text that hasn't changed materially since
+func (s *SummaryService) SummarizeBlog(blog models.Blog) error {
+	fingerprint := simhash(blog.Title + "\n" + plainText(blog.Content))
+	if s.isCurrent(blog, fingerprint) {
+		return nil
+	}
+
+	summary := s.summarize(blog)
+	return s.db.Model(&models.Blog{}).
+		// Skip the update if the blog was edited again while it was being summarized
+		Where("id = ? AND content = ?", blog.ID, blog.Content).
+		UpdateColumns(map[string]interface{}{
+			"summary":             summary.Summary,
+			"key_takeaways":       pq.StringArray(summary.KeyTakeaways),
+			"summary_source":      summary.Source,
+			"summary_fingerprint": fmt.Sprintf("%016x", fingerprint),
+		}).Error
+}
+
+// OnBlogSaved keeps summaries up to date; register it with BlogService.AddSaveListener
+func (s *SummaryService) OnBlogSaved(blog models.Blog) {
+	if err := s.SummarizeBlog(blog); err != nil {
+		log.Printf("Warning: Could not update summary for blog %s: %v", blog.ID, err)
+	}
+}
+
+// SummarizeMissing summarizes blogs that have never been summarized
+func (s *SummaryService) SummarizeMissing() error {
+	var blogs []models.Blog
+	if err := s.db.Where("summary_fingerprint IS NULL OR summary_fingerprint = ''").Find(&blogs).Error; err != nil {
+		return err
+	}
+
+	var failed int
+	for _, blog := range blogs {
+		if err := s.SummarizeBlog(blog); err != nil {
+			log.Printf("Warning: Could not summarize blog %s: %v", blog.ID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d blogs could not be summarized", failed, len(blogs))
+	}
+	return nil
+}
+
+// isCurrent reports whether the blog's stored summary still fits its text. An
+// extractive summary of a published post is replaced once AI summaries are available.
+func (s *SummaryService) isCurrent(blog models.Blog, fingerprint uint64) bool {
+	previous, err := strconv.ParseUint(blog.SummaryFingerprint, 16, 64)
+	if err != nil || bits.OnesCount64(previous^fingerprint) > summaryRefreshDistance {
+		return false
+	}
+	return blog.SummarySource == SummarySourceAI || !s.useAI(blog)
+}
+
+func (s *SummaryService) useAI(blog models.Blog) bool {
+	return s.aiService != nil && blog.Status == "published"
+}
+
+// summarize asks the AI service for a summary, falling back to an extractive one
+func (s *SummaryService) summarize(blog models.Blog) *BlogSummary {
+	if !s.useAI(blog) {
+		return extractiveSummary(blog.Content)
+	}
+
+	if s.usageService != nil {
+		if err := s.usageService.CheckQuota(blog.AuthorID); err != nil {
+			log.Printf("Warning: Using an extractive summary for blog %s: %v", blog.ID, err)
+			return extractiveSummary(blog.Content)
+		}
+	}
+
+	ctx, usage := TrackUsage(context.Background())
+	summary, err := s.aiService.SummarizeBlog(ctx, blog.Title, blog.Content)
+	if spent := usage.Usage(); s.usageService != nil && (spent.TotalTokens > 0 || spent.CacheHits > 0) {
+		if err := s.usageService.Record(blog.AuthorID, "summarize_blog", spent); err != nil {
+			log.Printf("Warning: Could not record AI usage for %s: %v", blog.AuthorID, err)
+		}
+	}
+	if err != nil {
+		log.Printf("Warning: Using an extractive summary for blog %s: %v", blog.ID, err)
+		return extractiveSummary(blog.Content)
+	}
+	return summary
+}
+
+// extractiveSummary builds a summary from a post's own sentences: the opening
+// sentences make the TL;DR, and the sentences that use the post's most frequent
+// words become the key takeaways
+func extractiveSummary(content string) *BlogSummary {
+	var sentences []string
+	for _, block := range markdownBlocks(content) {
+		if !block.Heading {
+			sentences = append(sentences, splitSentences(block.Text)...)
+		}
+	}
+
+	var lead []string
+	length := 0
+	for _, sentence := range sentences {
+		if length >= maxSummaryLength/2 || len(lead) == 3 {
+			break
+		}
+		lead = append(lead, sentence)
+		length += len([]rune(sentence)) + 1
+	}
+
+	candidates := sentences[len(lead):]
+	if len(candidates) < minTakeaways {
+		candidates = sentences
+	}
+
+	return &BlogSummary{
+		Summary:      truncateText(strings.Join(lead, " "), maxSummaryLength),
+		KeyTakeaways: keySentences(candidates, wordFrequencies(sentences)),
+		Source:       SummarySourceExtractive,
+	}
+}
+
+// keySentences picks 3 to 5 of the sentences whose words are most frequent in the
+// post, keeping them in their original order
+func keySentences(sentences []string, frequencies map[string]int) []string {
+	count := len(sentences) / 3
+	if count < minTakeaways {
+		count = minTakeaways
+	}
+	if count > maxTakeaways {
+		count = maxTakeaways
+	}
+	if count > len(sentences) {
+		count = len(sentences)
+	}
+
+	scores := make([]float64, len(sentences))
+	for i, sentence := range sentences {
+		var total, words int
+		for _, word := range wordsOf(sentence) {
+			if len([]rune(word)) < 4 || stopWords[word] {
+				continue
+			}
+			total += frequencies[stemWord(word)]
+			words++
+		}
+		if words > 0 {
+			scores[i] = float64(total) / float64(words)
+		}
+	}
+
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+	chosen := order[:count]
+	sort.Ints(chosen)
+
+	takeaways := make([]string, 0, count)
+	for _, i := range chosen {
+		takeaways = append(takeaways, truncateText(sentences[i], maxTakeawayLength))
+	}
+	return takeaways
+}
+
+// wordFrequencies counts the stemmed content words of the sentences
+func wordFrequencies(sentences []string) map[string]int {
+	frequencies := make(map[string]int)
+	for _, sentence := range sentences {
+		for _, word := range wordsOf(sentence) {
+			if len([]rune(word)) >= 4 && !stopWords[word] {
+				frequencies[stemWord(word)]++
+			}
+		}
+	}
+	return frequencies
+}
+
+// simhash fingerprints text so that similar texts get fingerprints differing in
+// few bits. Features are pairs of adjacent words.
+func simhash(text string) uint64 {
+	words := wordsOf(text)
+	if len(words) == 1 {
+		words = append(words, "")
+	}
+
+	var weights [64]int
+	for i := 0; i+1 < len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(words[i] + " " + words[i+1]))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"testing"
+
+	"ai-blog-backend/internal/models"
+)
+
+const summaryTestText = "The quick brown fox jumps over the lazy dog while the cat watches from the fence. " +
+	"Go makes it simple to build reliable and efficient software. Testing matters because regressions are costly and slow to find. " +
+	"Caching responses saves tokens, and retries with jitter avoid thundering herds when providers recover."
+
+func TestSimhash(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		materialEdit bool // The distance to summaryTestText exceeds summaryRefreshDistance
+		identical    bool // The fingerprint equals summaryTestText's
+	}{
+		{name: "same text", text: summaryTestText, identical: true},
+		{name: "case", text: strings.ToUpper(summaryTestText), identical: true},
+		{name: "punctuation and spacing", text: strings.Join(strings.Fields(strings.ReplaceAll(summaryTestText, ",", "")), "\n\n"), identical: true},
+		{name: "one word changed", text: strings.Replace(summaryTestText, "costly", "expensive", 1)},
+		{name: "sentence added", text: summaryTestText + " Small edits keep the summary."},
+		{
+			name:         "different post",
+			text:         "Baking sourdough bread requires patience, a lively starter and a hot oven. Let the dough rise overnight in the fridge for flavour.",
+			materialEdit: true,
+		},
+	}
+
+	base := simhash(summaryTestText)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := bits.OnesCount64(base ^ simhash(tt.text))
+			switch {
+			case tt.identical && distance != 0:
+				t.Errorf("fingerprints differ in %d bits, want identical fingerprints", distance)
+			case tt.materialEdit && distance <= summaryRefreshDistance:
+				t.Errorf("fingerprints differ in %d bits, want more than %d", distance, summaryRefreshDistance)
+			case !tt.materialEdit && distance > summaryRefreshDistance:
+				t.Errorf("fingerprints differ in %d bits, want at most %d", distance, summaryRefreshDistance)
+			}
+		})
+	}
+
+	if fp := simhash(""); fp != 0 {
+		t.Errorf("simhash(\"\") = %x, want 0", fp)
+	}
+	if fp := simhash("word"); fp == 0 {
+		t.Errorf("simhash(%q) = 0, want a fingerprint of the single word", "word")
+	}
+}
+
+func TestSummaryIsCurrent(t *testing.T) {
+	fingerprint := simhash(summaryTestText)
+	edited := simhash(strings.Replace(summaryTestText, "costly", "expensive", 1))
+	rewritten := simhash("Baking sourdough bread requires patience, a lively starter and a hot oven.")
+
+	tests := []struct {
+		name   string
+		blog   models.Blog
+		withAI bool
+		want   bool
+	}{
+		{
+			name: "never summarized",
+			blog: models.Blog{Status: "published"},
+			want: false,
+		},
+		{
+			name: "unchanged",
+			blog: models.Blog{Status: "published", SummarySource: SummarySourceAI, SummaryFingerprint: fmt.Sprintf("%016x", fingerprint)},
+			want: true,
+		},
+		{
+			name: "small edit",
+			blog: models.Blog{Status: "published", SummarySource: SummarySourceAI, SummaryFingerprint: fmt.Sprintf("%016x", edited)},
+			want: true,
+		},
+		{
+			name: "rewritten",
+			blog: models.Blog{Status: "published", SummarySource: SummarySourceAI, SummaryFingerprint: fmt.Sprintf("%016x", rewritten)},
+			want: false,
+		},
+		{
+			name:   "extractive summary once AI is available",
+			blog:   models.Blog{Status: "published", SummarySource: SummarySourceExtractive, SummaryFingerprint: fmt.Sprintf("%016x", fingerprint)},
+			withAI: true,
+			want:   false,
+		},
+		{
+			name:   "extractive summary of a draft",
+			blog:   models.Blog{Status: "draft", SummarySource: SummarySourceExtractive, SummaryFingerprint: fmt.Sprintf("%016x", fingerprint)},
+			withAI: true,
+			want:   true,
+		},
+		{
+			name: "extractive summary without AI",
+			blog: models.Blog{Status: "published", SummarySource: SummarySourceExtractive, SummaryFingerprint: fmt.Sprintf("%016x", fingerprint)},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SummaryService{}
+			if tt.withAI {
+				s.aiService = NewAIService(NewFakeGenerator(), nil, AITimeouts{})
+			}
+			if got := s.isCurrent(tt.blog, fingerprint); got != tt.want {
+				t.Errorf("isCurrent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+func init() {
+	// Use the encodings embedded in the binary rather than downloading them
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// tokenizers caches a *tiktoken.Tiktoken by model name, or nil for models without one
+var tokenizers sync.Map
+
+// tokenizer returns the tokenizer for a model, or nil if we don't know its encoding,
+// e.g. for self-hosted models
+func tokenizer(model string) *tiktoken.Tiktoken {
+	if cached, ok := tokenizers.Load(model); ok {
+		return cached.(*tiktoken.Tiktoken)
+	}
+
+	encoding, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		encoding = nil
+	}
+	tokenizers.Store(model, encoding)
+	return encoding
+}
+
+// countTokens returns the number of tokens text takes up for model, falling back
+// to estimateTokens for models without a known tokenizer
+func countTokens(model, text string) int {
+	if encoding := tokenizer(model); encoding != nil {
+		return len(encoding.EncodeOrdinary(text))
+	}
+	return estimateTokens(text)
+}
+
+// truncateTokens returns the longest prefix of text of at most maxTokens tokens for model
+func truncateTokens(model, text string, maxTokens int) string {
+	encoding := tokenizer(model)
+	if encoding == nil {
+		return firstRunes(text, maxTokens*4)
+	}
+
+	tokens := encoding.EncodeOrdinary(text)
+	if len(tokens) <= maxTokens {
+		return text
+	}
+	prefix := encoding.Decode(tokens[:maxTokens])
+	// A token can end partway through a character
+	for len(prefix) > 0 && !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+// cutTokens cuts text into pieces of at most maxTokens tokens for model, at
+// character boundaries
+func cutTokens(model, text string, maxTokens int) []string {
+	if maxTokens < 1 {
+		maxTokens = 1
+	}
+
+	// Byte offsets at which to cut
+	var cuts []int
+	if encoding := tokenizer(model); encoding != nil {
+		offset := 0
+		for i, token := range encoding.EncodeOrdinary(text) {
+			if i > 0 && i%maxTokens == 0 {
+				cuts = append(cuts, offset)
+			}
+			offset += len(encoding.Decode([]int{token}))
+		}
+	} else {
+		runes := 0
+		for offset := range text {
+			if runes > 0 && runes%(maxTokens*4) == 0 {
+				cuts = append(cuts, offset)
+			}
+			runes++
+		}
+	}
+
+	var pieces []string
+	start := 0
+	for _, cut := range cuts {
+		// A token can end partway through a character
+		for cut > start && cut < len(text) && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		if cut > start {
+			pieces = append(pieces, text[start:cut])
+			start = cut
+		}
+	}
+	return append(pieces, text[start:])
+}
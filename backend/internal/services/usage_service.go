@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"ai-blog-backend/internal/models"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// TokenUsage is the number of tokens spent on one AI request, across all the
+// model calls it made
+type TokenUsage struct {
+	Model            string   `json:"model"`
+	PromptTokens     int      `json:"promptTokens"`
+	CompletionTokens int      `json:"completionTokens"`
+	TotalTokens      int      `json:"totalTokens"`
+	CacheHits        int      `json:"cacheHits"`         // Model calls answered from the response cache
+	Prompts          []string `json:"prompts,omitempty"` // Prompt template versions used, e.g. generate_meta@v3
+}
+
+// UsageTracker accumulates the usage of the model calls made for one request
+type UsageTracker struct {
+	mu    sync.Mutex
+	usage TokenUsage
+}
+
+type usageTrackerKey struct{}
+
+// TrackUsage returns a context that accumulates the token usage of every model
+// call made with it. Nested calls share the outermost tracker, so a caller that
+// tracks usage itself sees what was spent even when the request fails.
+func TrackUsage(ctx context.Context) (context.Context, *UsageTracker) {
+	if tracker, ok := ctx.Value(usageTrackerKey{}).(*UsageTracker); ok {
+		return ctx, tracker
+	}
+	tracker := &UsageTracker{}
+	return context.WithValue(ctx, usageTrackerKey{}, tracker), tracker
+}
+
+// recordUsage adds a model call's token counts to the tracker in ctx, if any
+func recordUsage(ctx context.Context, req TextGenerationRequest, result *TextGenerationResult) {
+	tracker, ok := ctx.Value(usageTrackerKey{}).(*UsageTracker)
+	if !ok || result == nil {
+		return
+	}
+
+	// Cached responses cost nothing
+	if result.Cached {
+		tracker.mu.Lock()
+		defer tracker.mu.Unlock()
+		tracker.usage.Model = result.Model
+		tracker.usage.CacheHits++
+		return
+	}
+
+	promptTokens, completionTokens := result.PromptTokens, result.CompletionTokens
+	// Some providers (and all streaming responses) don't report usage
+	if promptTokens == 0 && completionTokens == 0 {
+		for _, msg := range req.Messages {
+			promptTokens += countTokens(result.Model, msg.Content)
+		}
+		completionTokens = countTokens(result.Model, result.Content)
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.usage.Model = result.Model
+	tracker.usage.PromptTokens += promptTokens
+	tracker.usage.CompletionTokens += completionTokens
+	tracker.usage.TotalTokens += promptTokens + completionTokens
+}
+
+// recordPrompt notes the prompt template version used by a request in the tracker in ctx, if any
+func recordPrompt(ctx context.Context, version string) {
+	tracker, ok := ctx.Value(usageTrackerKey{}).(*UsageTracker)
+	if !ok {
+		return
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	for _, recorded := range tracker.usage.Prompts {
+		if recorded == version {
+			return
+		}
+	}
+	tracker.usage.Prompts = append(tracker.usage.Prompts, version)
+}
+
+// promptVersions returns the prompt template versions recorded in ctx so far
+func promptVersions(ctx context.Context) []string {
+	tracker, ok := ctx.Value(usageTrackerKey{}).(*UsageTracker)
+	if !ok {
+		return nil
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return append([]string(nil), tracker.usage.Prompts...)
+}
+
+// Usage returns the tokens recorded so far
+func (t *UsageTracker) Usage() TokenUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage := t.usage
+	usage.Prompts = append([]string(nil), t.usage.Prompts...)
+	return usage
+}
+
+// estimateTokens approximates a token count at roughly four characters per token,
+// for models without a known tokenizer
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// AIQuota limits the tokens each user may spend. Zero means unlimited.
+type AIQuota struct {
+	DailyTokens   int
+	MonthlyTokens int
+}
+
+// ErrQuotaExceeded is matched by errors for users who have used up their AI quota
+var ErrQuotaExceeded = errors.New("AI usage quota exceeded")
+
+// QuotaExceededError reports which quota a user has used up and when it resets
+type QuotaExceededError struct {
+	Period   string // daily or monthly
+	Limit    int
+	Used     int
+	ResetsAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s AI quota of %d tokens exceeded", e.Period, e.Limit)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// UsagePeriod is a user's token usage within one quota period
+type UsagePeriod struct {
+	Used      int       `json:"used"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Unlimited bool      `json:"unlimited"`
+	ResetsAt  time.Time `json:"resetsAt"`
+	CacheHits int       `json:"cacheHits"` // Model calls answered from the response cache
+}
+
+type UsageSummary struct {
+	Daily   UsagePeriod `json:"daily"`
+	Monthly UsagePeriod `json:"monthly"`
+}
+
+type UsageService struct {
+	db    *gorm.DB
+	quota AIQuota
+}
+
+func NewUsageService(db *gorm.DB, quota AIQuota) *UsageService {
+	return &UsageService{db: db, quota: quota}
+}
+
+// GetUsage returns the user's token usage and remaining budget for the current day and month (UTC)
+func (s *UsageService) GetUsage(clerkUserID string) (*UsageSummary, error) {
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	daily, err := s.usageSince(clerkUserID, dayStart)
+	if err != nil {
+		return nil, err
+	}
+
+	monthly, err := s.usageSince(clerkUserID, monthStart)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &UsageSummary{
+		Daily:   usagePeriod(daily.Tokens, s.quota.DailyTokens, dayStart.AddDate(0, 0, 1)),
+		Monthly: usagePeriod(monthly.Tokens, s.quota.MonthlyTokens, monthStart.AddDate(0, 1, 0)),
+	}
+	summary.Daily.CacheHits = daily.CacheHits
+	summary.Monthly.CacheHits = monthly.CacheHits
+	return summary, nil
+}
+
+// CheckQuota returns a *QuotaExceededError if the user has no tokens left today or this month
+func (s *UsageService) CheckQuota(clerkUserID string) error {
+	return s.checkQuota(clerkUserID, 0)
+}
+
+// CheckDailyBudget returns a *QuotaExceededError if an account has spent limit
+// tokens today (UTC). It's for shared system accounts, which the per-user quota
+// doesn't suit. A limit of 0 or less is unlimited.
+func (s *UsageService) CheckDailyBudget(account string, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	daily, err := s.usageSince(account, dayStart)
+	if err != nil {
+		return err
+	}
+	if daily.Tokens >= limit {
+		return &QuotaExceededError{Period: "daily", Limit: limit, Used: daily.Tokens, ResetsAt: dayStart.AddDate(0, 0, 1)}
+	}
+	return nil
+}
+
+type quotaCheckKey struct{}
+
+// WithQuota returns a context in which operations that make several model calls
+// check the user's quota before each one, counting the tokens already spent by
+// the usage tracker in ctx that haven't been recorded yet
+func (s *UsageService) WithQuota(ctx context.Context, clerkUserID string) context.Context {
+	check := func(pending int) error {
+		return s.checkQuota(clerkUserID, pending)
+	}
+	return context.WithValue(ctx, quotaCheckKey{}, check)
+}
+
+// checkQuota runs the quota check set by UsageService.WithQuota, if any
+func checkQuota(ctx context.Context) error {
+	check, ok := ctx.Value(quotaCheckKey{}).(func(pending int) error)
+	if !ok {
+		return nil
+	}
+
+	pending := 0
+	if tracker, ok := ctx.Value(usageTrackerKey{}).(*UsageTracker); ok {
+		pending = tracker.Usage().TotalTokens
+	}
+	return check(pending)
+}
+
+func (s *UsageService) checkQuota(clerkUserID string, pending int) error {
+	if s.quota.DailyTokens <= 0 && s.quota.MonthlyTokens <= 0 {
+		return nil
+	}
+
+	summary, err := s.GetUsage(clerkUserID)
+	if err != nil {
+		return err
+	}
+	for _, period := range []*UsagePeriod{&summary.Daily, &summary.Monthly} {
+		*period = usagePeriod(period.Used+pending, period.Limit, period.ResetsAt)
+	}
+
+	if !summary.Daily.Unlimited && summary.Daily.Remaining <= 0 {
+		return &QuotaExceededError{Period: "daily", Limit: summary.Daily.Limit, Used: summary.Daily.Used, ResetsAt: summary.Daily.ResetsAt}
+	}
+	if !summary.Monthly.Unlimited && summary.Monthly.Remaining <= 0 {
+		return &QuotaExceededError{Period: "monthly", Limit: summary.Monthly.Limit, Used: summary.Monthly.Used, ResetsAt: summary.Monthly.ResetsAt}
+	}
+	return nil
+}
+
+// Record adds an entry to the usage ledger
+func (s *UsageService) Record(clerkUserID, operation string, usage TokenUsage) error {
+	entry := models.AIUsage{
+		ClerkUserID:      clerkUserID,
+		Operation:        operation,
+		Model:            usage.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		CacheHits:        usage.CacheHits,
+		Prompts:          pq.StringArray(usage.Prompts),
+	}
+	return s.db.Create(&entry).Error
+}
+
+type usageTotals struct {
+	Tokens    int
+	CacheHits int
+}
+
+func (s *UsageService) usageSince(clerkUserID string, since time.Time) (usageTotals, error) {
+	var totals usageTotals
+	err := s.db.Model(&models.AIUsage{}).
+		Select("COALESCE(SUM(total_tokens), 0) AS tokens, COALESCE(SUM(cache_hits), 0) AS cache_hits").
+		Where("clerk_user_id = ? AND created_at >= ?", clerkUserID, since).
+		Scan(&totals).Error
+	return totals, err
+}
+
+func usagePeriod(used, limit int, resetsAt time.Time) UsagePeriod {
+	period := UsagePeriod{Used: used, Limit: limit, ResetsAt: resetsAt}
+	if limit <= 0 {
+		period.Unlimited = true
+		return period
+	}
+
+	period.Remaining = limit - used
+	if period.Remaining < 0 {
+		period.Remaining = 0
+	}
+	return period
+}
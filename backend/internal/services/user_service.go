@@ -74,6 +74,30 @@ func (s *UserService) UpdateUserProfile(clerkUserID string, req UpdateUserProfil
 	return &profile, err
 }
 
+// GetVoiceProfile returns the user's saved voice profile, or nil if they have none
+func (s *UserService) GetVoiceProfile(clerkUserID string) (*models.VoiceProfile, error) {
+	var profiles []models.UserProfile
+	err := s.db.Select("voice_profile").Where("clerk_user_id = ?", clerkUserID).Limit(1).Find(&profiles).Error
+	if err != nil || len(profiles) == 0 {
+		return nil, err
+	}
+	return profiles[0].VoiceProfile, nil
+}
+
+// UpdateVoiceProfile saves the user's voice profile, replacing any previous one
+func (s *UserService) UpdateVoiceProfile(clerkUserID string, voice models.VoiceProfile) (*models.VoiceProfile, error) {
+	profile, err := s.GetOrCreateUserProfile(clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Model(profile).Update("voice_profile", voice).Error
+	if err != nil {
+		return nil, err
+	}
+	return &voice, nil
+}
+
 // UpdateLastActivity updates user's last active timestamp
 func (s *UserService) UpdateLastActivity(clerkUserID string) error {
 	now := time.Now()
@@ -1,3 +1,6 @@
+// The backend serves the sitemap and robots.txt, which crawlers expect at the site root
+const apiOrigin = new URL(process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080/api').origin
+
 /** @type {import('next').NextConfig} */
 const nextConfig = {
   images: {
@@ -15,6 +18,13 @@ const nextConfig = {
     CLERK_SECRET_KEY: process.env.CLERK_SECRET_KEY,
     NEXT_PUBLIC_API_URL: process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080/api',
   },
+  async rewrites() {
+    return [
+      { source: '/sitemap.xml', destination: `${apiOrigin}/sitemap.xml` },
+      { source: '/sitemaps/:file', destination: `${apiOrigin}/sitemaps/:file` },
+      { source: '/robots.txt', destination: `${apiOrigin}/robots.txt` },
+    ]
+  },
 }
 
 module.exports = nextConfig 
\ No newline at end of file
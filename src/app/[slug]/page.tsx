@@ -2,8 +2,8 @@
 
 import { useEffect, useState } from 'react'
 import { useParams } from 'next/navigation'
-import ReactMarkdown from 'react-markdown'
 import Header from '@/components/Header'
+import PostContent from '@/components/PostContent'
 import { blogAPI } from '@/lib/api'
 import type { Blog } from '@/types/blog'
 
@@ -141,9 +141,7 @@ export default function BlogPost() {
             
             {/* Article Content */}
             <div className="prose prose-lg max-w-none">
-              <ReactMarkdown>
-                {blog.content}
-              </ReactMarkdown>
+              <PostContent content={blog.content} />
             </div>
             
             {/* Article Footer */}
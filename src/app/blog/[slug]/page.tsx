@@ -2,8 +2,8 @@
 
 import { useEffect, useState } from 'react'
 import { useParams } from 'next/navigation'
-import ReactMarkdown from 'react-markdown'
 import Header from '@/components/Header'
+import PostContent from '@/components/PostContent'
 import LikeButton from '@/components/LikeButton'
 import ShareButton from '@/components/ShareButton'
 import CommentSection from '@/components/CommentSection'
@@ -144,9 +144,7 @@ export default function BlogPost() {
             
             {/* Article Content */}
             <div className="prose prose-lg max-w-none">
-              <ReactMarkdown>
-                {blog.content}
-              </ReactMarkdown>
+              <PostContent content={blog.content} />
             </div>
             
             {/* Article Footer */}
@@ -0,0 +1,39 @@
+'use client'
+
+import ReactMarkdown from 'react-markdown'
+
+interface PostContentProps {
+  content: string
+}
+
+// Splits markdown into blocks the way the backend numbers citation anchors:
+// one block per paragraph, with a fenced code block kept whole
+function splitBlocks(content: string): string[] {
+  const blocks: string[] = []
+  let inCode = false
+  for (const paragraph of content.split('\n\n')) {
+    if (inCode) {
+      blocks[blocks.length - 1] += '\n\n' + paragraph
+    } else {
+      blocks.push(paragraph)
+    }
+    if ((paragraph.trim().split('```').length - 1) % 2 === 1) {
+      inCode = !inCode
+    }
+  }
+  return blocks
+}
+
+// Renders a post with each block anchored as p-1, p-2, ... so answers from
+// "Ask the blog" can link to the paragraph they cite
+export default function PostContent({ content }: PostContentProps) {
+  return (
+    <>
+      {splitBlocks(content).map((block, i) => (
+        <div key={i} id={`p-${i + 1}`} className="scroll-mt-24">
+          <ReactMarkdown>{block}</ReactMarkdown>
+        </div>
+      ))}
+    </>
+  )
+}